@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// apiSession is a session started through the API, running in the background
+type apiSession struct {
+	id       int
+	players  *playerPair
+	opts     sessionOptions
+	progress sessionProgress
+	stop     chan struct{} // closed to stop the session
+	stopOnce sync.Once
+	done     chan struct{} // closed when the session has ended
+	err      error         // failure that ended the session early; set before done is closed
+}
+
+// sessionInfo describes the progress of a session
+type sessionInfo struct {
+	ID       int       `json:"id"`
+	Players  [2]string `json:"players"`
+	Board    string    `json:"board"`
+	Episodes int       `json:"episodes"` // number of episodes asked for
+	Played   int       `json:"played"`   // number of episodes played so far
+	Wins     [2]int    `json:"wins"`     // number of episodes won by each player, in the order of the players
+	Draws    int       `json:"draws"`
+	State    string    `json:"state"`           // "running", "stopping", "stopped", "failed" or "done"
+	Error    string    `json:"error,omitempty"` // failure of a failed session
+}
+
+func (as *apiSession) info() sessionInfo {
+	played, result := as.progress.get()
+	si := sessionInfo{
+		ID: as.id, Players: [2]string{as.players[0].name, as.players[1].name}, Board: as.opts.spec.String(),
+		Episodes: as.opts.episodes, Played: played, Wins: result.wins, Draws: result.draws, State: "running",
+	}
+	select {
+	case <-as.done:
+		si.State = "done"
+		if as.err != nil {
+			si.State, si.Error = "failed", as.err.Error()
+		} else if played < as.opts.episodes {
+			si.State = "stopped"
+		}
+	default:
+		if as.opts.stopped() {
+			si.State = "stopping"
+		}
+	}
+	return si
+}
+
+// GET /api/players lists the players; POST /api/players creates a player declared like in a
+// config file
+func (s *server) handlePlayers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		infos := []playerInfo{}
+		for _, p := range s.players {
+			infos = append(infos, s.playerInfo(p))
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, infos)
+	case http.MethodPost:
+		var pc playerConfig
+		if err := readJSON(r, &pc); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		err := pc.validate()
+		if err == nil {
+			err = validateAPIPlayer(pc)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.mu.Lock()
+		taken := s.findPlayer(pc.Name) != nil
+		s.mu.Unlock()
+		if taken {
+			writeError(w, http.StatusConflict, fmt.Errorf("player %v exists", pc.Name))
+			return
+		}
+		p, err := pc.createPlayer()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.findPlayer(pc.Name) != nil { // created by another request meanwhile
+			writeError(w, http.StatusConflict, fmt.Errorf("player %v exists", pc.Name))
+			return
+		}
+		s.players = append(s.players, &p)
+		writeJSON(w, http.StatusCreated, s.playerInfo(&p))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+	}
+}
+
+// check what the API can't let a client choose, on top of what a config file can: the files of
+// the player are named after it, so the name can't leave the working directory, nor can the
+// model files it loads; a human only plays at the terminal, and an engine, which runs a command
+// on the server, is only declared by the owner of the server in a config file
+func validateAPIPlayer(pc playerConfig) error {
+	if pc.Name == "" {
+		return fmt.Errorf("the player has no name")
+	}
+	for _, c := range pc.Name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("player name %q has a character other than letters, digits, \"_\", \"-\" and \".\"", pc.Name)
+		}
+	}
+	if strings.HasPrefix(pc.Name, ".") {
+		return fmt.Errorf("player name %q starts with \".\"", pc.Name)
+	}
+	for _, file := range []string{pc.Model, pc.Prior} {
+		if file != "" && !filepath.IsLocal(file) {
+			return fmt.Errorf("model file %q of player %v is not in the working directory", file, pc.Name)
+		}
+	}
+	if pc.Being == "human" {
+		return fmt.Errorf("human player %v can only play at the terminal", pc.Name)
+	}
+	if pc.Being == "external" {
+		return fmt.Errorf("external player %v can only be declared in a config file", pc.Name)
+	}
+	return nil
+}
+
+// valueRequest asks a robot for its value of a board
+type valueRequest struct {
+	Board  board  `json:"board"`  // rows of "x", "o" or "" for an empty location
+	Symbol string `json:"symbol"` // symbol the robot plays
+}
+
+// GET /api/players/{name} describes the player; POST /api/players/{name}/value returns a
+// robot's value of a board; GET /api/players/{name}/model downloads a robot's model file
+func (s *server) handlePlayer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/players/"), "/")
+	s.mu.Lock()
+	p := s.findPlayer(parts[0])
+	var info playerInfo
+	if p != nil {
+		info = s.playerInfo(p)
+	}
+	s.mu.Unlock()
+	if p == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no player %q", parts[0]))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, info)
+	case len(parts) == 2 && parts[1] == "value" && r.Method == http.MethodPost:
+		var req valueRequest
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.busy[p] { // its values change under a running session
+			writeError(w, http.StatusConflict, fmt.Errorf("%v is playing a game or a session", p.name))
+			return
+		}
+		value, known, err := boardValue(p, req.Board, req.Symbol)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"value": value, "known": known})
+	case len(parts) == 2 && parts[1] == "model" && r.Method == http.MethodGet:
+		if info.Kind != "robot" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%v is a %v, which has no model", p.name, info.Kind))
+			return
+		}
+		filename := modelFilename(p.name)
+		if _, err := os.Stat(filename); err != nil {
+			writeError(w, http.StatusNotFound, fmt.Errorf("%v has no model saved yet", p.name))
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		http.ServeFile(w, r, filename)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v %v not allowed", r.Method, r.URL.Path))
+	}
+}
+
+// check that the board is a rectangle of symbols and the symbol is one of the players'
+func (req valueRequest) validate() error {
+	if len(req.Board) == 0 || len(req.Board[0]) == 0 {
+		return fmt.Errorf("the board is empty")
+	}
+	// any win length fits the size; states don't encode it
+	if err := (boardSpec{rows: len(req.Board), cols: len(req.Board[0]), k: 1}).validate(); err != nil {
+		return err
+	}
+	for _, row := range req.Board {
+		if len(row) != len(req.Board[0]) {
+			return fmt.Errorf("the rows of the board have different lengths")
+		}
+		for _, element := range row {
+			if element != "" && element != "x" && element != "o" {
+				return fmt.Errorf("invalid symbol %q on the board", element)
+			}
+		}
+	}
+	if req.Symbol != "x" && req.Symbol != "o" {
+		return fmt.Errorf("invalid symbol %q", req.Symbol)
+	}
+	return nil
+}
+
+// value of the board in the perspective of the robot playing symbol: a robot learning state
+// values gives the value of the state, a Q-learning robot the value of its best move. known
+// tells whether the robot has learnt the value, rather than taken the default one.
+func boardValue(p *player, b board, symbol string) (float64, bool, error) {
+	switch a := p.agent.(type) {
+	case *robot:
+		state := a.encodeState(&b, symbol)
+		_, known := a.mind.values[state]
+		return a.stateValue(state), known, nil
+	case *qRobot:
+		if getEmpties(b) == 0 {
+			return 0, false, fmt.Errorf("the board has no move left")
+		}
+		best, value := a.bestAction(b, symbol)
+		_, known := a.qvalues[best]
+		return value, known, nil
+	}
+	return 0, false, fmt.Errorf("%v is a %v, which has no values", p.name, p.agent.kind())
+}
+
+// newSessionRequest is the body of a request to start a session
+type newSessionRequest struct {
+	Players  []string     `json:"players"`  // names of the two players
+	Episodes int          `json:"episodes"` // number of episodes
+	Board    *boardConfig `json:"board"`    // default board if omitted
+}
+
+// GET /api/sessions lists the sessions started through the API; POST /api/sessions starts a
+// session in the background
+func (s *server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		infos := []sessionInfo{}
+		for id := 0; id < s.nextSessionID; id++ {
+			infos = append(infos, s.sessions[id].info())
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, infos)
+	case http.MethodPost:
+		var req newSessionRequest
+		if err := readJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		spec := sessionConfig{Board: req.Board}.spec()
+		err := spec.validate()
+		if err == nil && len(req.Players) != 2 {
+			err = fmt.Errorf("%v players, expected 2", len(req.Players))
+		}
+		if err == nil && req.Episodes < 1 {
+			err = fmt.Errorf("invalid number of episodes %v", req.Episodes)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// take the players
+		s.mu.Lock()
+		var ps playerPair
+		for i, name := range req.Players {
+			ps[i] = s.findPlayer(name)
+			if ps[i] == nil || ps[i].agent.kind() == "human" {
+				s.mu.Unlock()
+				writeError(w, http.StatusNotFound, fmt.Errorf("no player %q to run a session", name))
+				return
+			}
+		}
+		if ps[0] == ps[1] {
+			s.mu.Unlock()
+			writeError(w, http.StatusBadRequest, fmt.Errorf("player %v cannot play against itself", ps[0].name))
+			return
+		}
+		for _, p := range ps {
+			if s.busy[p] {
+				s.mu.Unlock()
+				writeError(w, http.StatusConflict, fmt.Errorf("%v is playing a game or a session", p.name))
+				return
+			}
+		}
+		s.busy[ps[0]], s.busy[ps[1]] = true, true
+		as := &apiSession{id: s.nextSessionID, players: &ps, stop: make(chan struct{}), done: make(chan struct{})}
+		as.opts = sessionOptions{episodes: req.Episodes, spec: spec, first: randomFirst, ratings: s.ratings, rng: newRand(), games: s.log, progress: &as.progress, stop: as.stop}
+		s.sessions[as.id] = as
+		s.nextSessionID++
+		info := as.info()
+		s.mu.Unlock()
+
+		go func() {
+			_, as.err = runSession(as.players, as.opts)
+			close(as.done)
+			s.mu.Lock()
+			s.busy[ps[0]], s.busy[ps[1]] = false, false
+			s.mu.Unlock()
+		}()
+		writeJSON(w, http.StatusCreated, info)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+	}
+}
+
+// GET /api/sessions/{id} returns the progress of the session; POST /api/sessions/{id}/stop
+// stops it after the current episode
+func (s *server) handleSession(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	as, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %v", id))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, as.info())
+	case len(parts) == 2 && parts[1] == "stop" && r.Method == http.MethodPost:
+		as.stopOnce.Do(func() { close(as.stop) })
+		writeJSON(w, http.StatusOK, as.info())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v %v not allowed", r.Method, r.URL.Path))
+	}
+}
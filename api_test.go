@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// a board of the value request is at most maxBoardSize wide and long, like any other board
+func TestValueRequestValidate(t *testing.T) {
+	wide := make(board, 1)
+	wide[0] = make([]string, maxBoardSize+1)
+	tall := make(board, maxBoardSize+1)
+	for i := range tall {
+		tall[i] = []string{""}
+	}
+	for _, b := range []board{wide, tall} {
+		if err := (valueRequest{Board: b, Symbol: "x"}).validate(); err == nil || !strings.Contains(err.Error(), "larger than") {
+			t.Errorf("%vx%v board: got error %v", len(b), len(b[0]), err)
+		}
+	}
+	largest := make(board, maxBoardSize)
+	for i := range largest {
+		largest[i] = make([]string, maxBoardSize)
+	}
+	if err := (valueRequest{Board: largest, Symbol: "x"}).validate(); err != nil {
+		t.Errorf("%vx%v board: %v", maxBoardSize, maxBoardSize, err)
+	}
+}
+
+// a session that ended with a failure is reported as failed, with its failure
+func TestAPISessionFailed(t *testing.T) {
+	as := &apiSession{
+		players: &playerPair{{name: "p1"}, {name: "p2"}},
+		opts:    sessionOptions{episodes: 3, spec: defaultSpec},
+		done:    make(chan struct{}),
+		err:     errors.New("cannot save ratings: disk full"),
+	}
+	close(as.done)
+	if si := as.info(); si.State != "failed" || si.Error != as.err.Error() {
+		t.Errorf("got state %q and error %q, want failed with %q", si.State, si.Error, as.err)
+	}
+}
+
+// names and model files of API players stay inside the working directory
+func TestValidateAPIPlayer(t *testing.T) {
+	bad := []playerConfig{
+		{Name: "", Being: "robot"},
+		{Name: "../escape", Being: "robot"},
+		{Name: "a/b", Being: "robot"},
+		{Name: ".hidden", Being: "robot"},
+		{Name: "r", Being: "robot", Model: "../r.json"},
+		{Name: "r", Being: "robot", Model: "/tmp/r.json"},
+		{Name: "m", Being: "mcts", Prior: "../r.json"},
+		{Name: "h", Being: "human"},
+	}
+	for _, pc := range bad {
+		if err := validateAPIPlayer(pc); err == nil {
+			t.Errorf("player %+v is accepted", pc)
+		}
+	}
+	good := []playerConfig{
+		{Name: "r1", Being: "robot"},
+		{Name: "r_2.v-3", Being: "robot", Model: "models/r.json"},
+		{Name: "m", Being: "mcts", Prior: "r.json"},
+	}
+	for _, pc := range good {
+		if err := validateAPIPlayer(pc); err != nil {
+			t.Errorf("player %+v: %v", pc, err)
+		}
+	}
+}
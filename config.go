@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// config declares the players and the ordered sessions run in batch mode; a session may
+// also be a whole tournament
+type config struct {
+	Players  []playerConfig  `json:"players"`
+	Sessions []sessionConfig `json:"sessions"`
+}
+
+type playerConfig struct {
+	Name    string        `json:"name"`
+	Being   string        `json:"being"`   // "robot", "human", "minimax", "mcts" or "external"
+	Algo    string        `json:"algo"`    // learning algorithm of a robot, "mc" (default), "td" or "q"
+	Alp     *schedule     `json:"alp"`     // number or schedule, e.g. "exp:0.5:0.999"; default alpha if omitted
+	Eps     *schedule     `json:"eps"`     // number or schedule; default epsilon if omitted
+	Gam     *float64      `json:"gam"`     // default gamma if omitted
+	Lam     float64       `json:"lam"`     // lambda of a "td" robot
+	Sym     bool          `json:"sym"`     // learn on canonical states
+	Explore exploration   `json:"explore"` // exploration policy, e.g. "softmax:0.1"; eps-greedy if omitted
+	Rewards *rewardConfig `json:"rewards"` // rewards and shaping terms of a robot; default rewards if omitted
+	Model   string        `json:"model"`   // model file to load the robot from; specs are then taken from the model
+	Depth   int           `json:"depth"`   // search depth of a minimax player; unlimited if zero
+
+	Playouts int    `json:"playouts"` // playouts per move of an mcts player
+	Time     string `json:"time"`     // time per move of an mcts player, e.g. "200ms"
+	Rollout  string `json:"rollout"`  // rollouts of an mcts player, "random" (default) or "value"
+	Prior    string `json:"prior"`    // model file of the robot whose values guide an mcts player
+
+	Command []string `json:"command"` // command line of an external engine, e.g. ["python3", "agent.py"]
+}
+
+// check whether any spec only meant for mcts players is set
+func (pc playerConfig) hasMCTSSpecs() bool {
+	return pc.Playouts != 0 || pc.Time != "" || pc.Rollout != "" || pc.Prior != ""
+}
+
+// check whether any spec only meant for robots is set
+func (pc playerConfig) hasRobotSpecs() bool {
+	return pc.Algo != "" || pc.Lam != 0 || pc.Alp != nil || pc.Eps != nil || pc.Gam != nil || pc.Sym || pc.Explore.policy != "" || pc.Rewards != nil || pc.Model != ""
+}
+
+// rewardConfig are the rewards of a robot; an omitted outcome takes the default reward, and an
+// omitted shaping term is zero
+type rewardConfig struct {
+	Win    *float64 `json:"win"`
+	Draw   *float64 `json:"draw"`
+	Lose   *float64 `json:"lose"`
+	Threat float64  `json:"threat"` // per threat created: a line of k with k-1 own symbols and an empty location
+	Block  float64  `json:"block"`  // per threat of the opponent blocked
+	Move   float64  `json:"move"`   // per move; a penalty if negative
+}
+
+// rewards of the robot, the default ones if not configured
+func (rc *rewardConfig) specs() rewardSpecs {
+	rw := defaultRewards
+	if rc == nil {
+		return rw
+	}
+	if rc.Win != nil {
+		rw.win = *rc.Win
+	}
+	if rc.Draw != nil {
+		rw.draw = *rc.Draw
+	}
+	if rc.Lose != nil {
+		rw.lose = *rc.Lose
+	}
+	rw.threat, rw.block, rw.move = rc.Threat, rc.Block, rc.Move
+	return rw
+}
+
+type sessionConfig struct {
+	Players    []string     `json:"players"`    // names of the two players; of the tournament players, all if omitted
+	Episodes   int          `json:"episodes"`   // number of episodes of the session, or of each session of the tournament
+	Verbose    bool         `json:"verbose"`    // robots print their plans
+	Board      *boardConfig `json:"board"`      // default board if omitted
+	Tournament string       `json:"tournament"` // tournament format, "rr", "drr" or "swiss", if the session is a tournament
+	Rounds     int          `json:"rounds"`     // number of swiss rounds; default if omitted
+}
+
+type boardConfig struct {
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+	K    int `json:"k"`
+}
+
+// read and validate a config file
+func loadConfig(filename string) (*config, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cfg config
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("cannot read config %v: %v", filename, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %v: %v", filename, err)
+	}
+	return &cfg, nil
+}
+
+// check the config for anything that would stop the run halfway
+func (cfg *config) validate() error {
+	if len(cfg.Players) < 2 {
+		return fmt.Errorf("at least 2 players are needed, got %v", len(cfg.Players))
+	}
+	names := map[string]bool{}
+	for i, pc := range cfg.Players {
+		if pc.Name == "" {
+			return fmt.Errorf("player #%v has no name", i)
+		}
+		if names[pc.Name] {
+			return fmt.Errorf("player %v is declared twice", pc.Name)
+		}
+		names[pc.Name] = true
+		if err := pc.validate(); err != nil {
+			return err
+		}
+	}
+	for i, sc := range cfg.Sessions {
+		seen := map[string]bool{}
+		for _, name := range sc.Players {
+			if !names[name] {
+				return fmt.Errorf("session #%v has unknown player %q", i, name)
+			}
+			if seen[name] {
+				return fmt.Errorf("session #%v has player %v playing against itself", i, name)
+			}
+			seen[name] = true
+		}
+		switch sc.Tournament {
+		case "":
+			if len(sc.Players) != 2 {
+				return fmt.Errorf("session #%v has %v players, expected 2", i, len(sc.Players))
+			}
+		case roundRobin, doubleRoundRobin, swiss:
+			if len(sc.Players) == 1 {
+				return fmt.Errorf("tournament #%v has only 1 player", i)
+			}
+		default:
+			return fmt.Errorf("session #%v has unknown tournament format %q", i, sc.Tournament)
+		}
+		if sc.Rounds < 0 || (sc.Rounds > 0 && sc.Tournament != swiss) {
+			return fmt.Errorf("session #%v has %v rounds but is not a swiss tournament", i, sc.Rounds)
+		}
+		if sc.Episodes < 1 {
+			return fmt.Errorf("session #%v has %v episodes", i, sc.Episodes)
+		}
+		if err := sc.spec().validate(); err != nil {
+			return fmt.Errorf("session #%v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// check the specs of a player
+func (pc playerConfig) validate() error {
+	if pc.Being != "minimax" && pc.Depth != 0 {
+		return fmt.Errorf("player %v has a search depth but is a %v", pc.Name, pc.Being)
+	}
+	if pc.Being != "mcts" && pc.hasMCTSSpecs() {
+		return fmt.Errorf("player %v has tree search specs but is a %v", pc.Name, pc.Being)
+	}
+	if pc.Being != "external" && len(pc.Command) > 0 {
+		return fmt.Errorf("player %v has an engine command but is a %v", pc.Name, pc.Being)
+	}
+	if pc.Being == "external" && len(pc.Command) == 0 {
+		return fmt.Errorf("external player %v has no engine command", pc.Name)
+	}
+	switch pc.Being {
+	case "robot":
+		if pc.Algo != "" && pc.Algo != monteCarlo && pc.Algo != temporalDifference && pc.Algo != qLearning {
+			return fmt.Errorf("robot %v has unknown algorithm %q", pc.Name, pc.Algo)
+		}
+		if pc.Lam != 0 && pc.Algo != temporalDifference {
+			return fmt.Errorf("robot %v has lambda but algorithm %q", pc.Name, pc.Algo)
+		}
+		if pc.Algo == qLearning && pc.Sym {
+			return fmt.Errorf("robot %v cannot learn on canonical states with algorithm %q", pc.Name, pc.Algo)
+		}
+	case "human", "minimax", "mcts", "external":
+		if pc.hasRobotSpecs() {
+			return fmt.Errorf("%v player %v cannot have robot specs or a model", pc.Being, pc.Name)
+		}
+		if pc.Depth < 0 {
+			return fmt.Errorf("player %v has a negative search depth", pc.Name)
+		}
+		if pc.Playouts < 0 {
+			return fmt.Errorf("player %v has a negative number of playouts", pc.Name)
+		}
+		if _, err := pc.budget(); err != nil {
+			return fmt.Errorf("player %v: %v", pc.Name, err)
+		}
+		if pc.Rollout != "" && pc.Rollout != randomRollout && pc.Rollout != valueRollout {
+			return fmt.Errorf("player %v has unknown rollout %q", pc.Name, pc.Rollout)
+		}
+		if pc.Rollout == valueRollout && pc.Prior == "" {
+			return fmt.Errorf("player %v needs a prior model for %q rollouts", pc.Name, pc.Rollout)
+		}
+	default:
+		return fmt.Errorf("player %v is an unknown creature %q", pc.Name, pc.Being)
+	}
+	for _, spec := range []*float64{pc.Gam, &pc.Lam} {
+		if spec != nil && (*spec < 0 || *spec > 1) {
+			return fmt.Errorf("player %v has specs out of [0, 1]", pc.Name)
+		}
+	}
+	for _, s := range []*schedule{pc.Alp, pc.Eps} {
+		if s == nil {
+			continue
+		}
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("player %v: %v", pc.Name, err)
+		}
+	}
+	return nil
+}
+
+// time per move of an mcts player; none if not set
+func (pc playerConfig) budget() (time.Duration, error) {
+	if pc.Time == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(pc.Time)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time per move %q", pc.Time)
+	}
+	return d, nil
+}
+
+// board spec of the session
+func (sc sessionConfig) spec() boardSpec {
+	if sc.Board == nil {
+		return defaultSpec
+	}
+	return boardSpec{rows: sc.Board.Rows, cols: sc.Board.Cols, k: sc.Board.K}
+}
+
+// create the players declared in the config
+func (cfg *config) createPlayers() ([]player, error) {
+	players := make([]player, len(cfg.Players))
+	for i, pc := range cfg.Players {
+		p, err := pc.createPlayer()
+		if err != nil {
+			return nil, err
+		}
+		players[i] = p
+	}
+	fmt.Print("*** Done creating players *** \n\n")
+	return players, nil
+}
+
+// create the player of a validated config
+func (pc playerConfig) createPlayer() (player, error) {
+	switch pc.Being {
+	case "human":
+		return player{name: pc.Name, agent: &human{}}, nil
+	case "minimax":
+		return player{name: pc.Name, agent: newMinimax(pc.Name, pc.Depth)}, nil
+	case "external":
+		return player{name: pc.Name, agent: newExternal(pc.Name, pc.Command)}, nil
+	case "mcts":
+		var prior *robot
+		if pc.Prior != "" {
+			var err error
+			if prior, err = loadPrior(pc.Prior); err != nil {
+				return player{}, err
+			}
+		}
+		budget, _ := pc.budget() // validated
+		return player{name: pc.Name, agent: newMCTS(pc.Name, pc.Playouts, budget, pc.Rollout, prior)}, nil
+	}
+	if pc.Model != "" {
+		var p player
+		err := p.loadModel(pc.Name, pc.Model)
+		return p, err
+	}
+	rs := robotSpecs{algo: pc.Algo, alp: constantSchedule(alpha), eps: constantSchedule(epsilon), gam: gamma, lam: pc.Lam, sym: pc.Sym, explore: pc.Explore, rewards: pc.Rewards.specs()}
+	if pc.Alp != nil {
+		rs.alp = *pc.Alp
+	}
+	if pc.Eps != nil {
+		rs.eps = *pc.Eps
+	}
+	if pc.Gam != nil {
+		rs.gam = *pc.Gam
+	}
+	return player{name: pc.Name, agent: createRobot(pc.Name, rs)}, nil
+}
+
+// run the sessions of the config without prompts, on up to the given number of goroutines;
+// sessions sharing a player run in the order of the config. The failures of the sessions are
+// returned once all have run.
+func runBatch(cfg *config, ratings *ratingBook, games *gameLog, workers int) error {
+	players, err := cfg.createPlayers()
+	if err != nil {
+		return err
+	}
+	index := make(map[string]int, len(players))
+	for i, p := range players {
+		index[p.name] = i
+	}
+	jobs := make([]job, len(cfg.Sessions))
+	for i, sc := range cfg.Sessions {
+		opts := sessionOptions{episodes: sc.Episodes, spec: sc.spec(), verbose: sc.Verbose, first: randomFirst, ratings: ratings, rng: newRand(), games: games}
+		if sc.Tournament == "" {
+			ps := &playerPair{&players[index[sc.Players[0]]], &players[index[sc.Players[1]]]}
+			jobs[i] = job{
+				players: ps[:],
+				run: func() (*sessionResult, error) {
+					result, err := runSession(ps, opts)
+					return &result, err
+				},
+			}
+			continue
+		}
+		var entrants []*player
+		for i := range players {
+			entrants = append(entrants, &players[i])
+		}
+		if len(sc.Players) > 0 {
+			entrants = entrants[:0]
+			for _, name := range sc.Players {
+				entrants = append(entrants, &players[index[name]])
+			}
+		}
+		t := tournament{format: sc.Tournament, rounds: sc.Rounds, opts: opts}
+		if t.rounds == 0 {
+			t.rounds = defaultSwissRounds(len(entrants))
+		}
+		jobs[i] = job{
+			players: entrants,
+			run: func() (*sessionResult, error) {
+				return nil, runTournament(entrants, t)
+			},
+		}
+	}
+	results, errs := runJobs(jobs, workers)
+	printBatchSummary(jobs, results, errs)
+	var failures []error
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Errorf("session #%v failed: %v", i, err))
+		}
+	}
+	return errors.Join(failures...)
+}
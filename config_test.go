@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// a batch returns the failures of its sessions once all have run
+func TestBatchFailures(t *testing.T) {
+	cfg := &config{
+		Players: []playerConfig{
+			{Name: "mm", Being: "minimax"},
+			{Name: "mm2", Being: "minimax"},
+			{Name: "ext", Being: "external", Command: []string{"sh", "-c", "exit 0"}},
+		},
+		Sessions: []sessionConfig{
+			{Players: []string{"mm", "ext"}, Episodes: 2},
+			{Players: []string{"mm", "mm2"}, Episodes: 2},
+		},
+	}
+	err := runBatch(cfg, nil, nil, 2)
+	if err == nil || !strings.Contains(err.Error(), "ext failed") {
+		t.Errorf("got error %v, want the failure of ext", err)
+	}
+}
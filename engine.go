@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The engine protocol lets an agent outside GoTick play in its sessions, and lets an outside
+// arena drive a GoTick robot. The host sends one command per line and the engine answers each
+// command with one line: "ok", "move <row> <col>" for genmove, or "error <message>".
+//
+//	newgame                 a new episode starts on an empty board
+//	board <rows> <cols> <k> size of the board and win length of the episode
+//	symbol <x|o>            symbol the engine plays in the episode; "x" moves first
+//	moved <row> <col>       the opponent moved at the location
+//	genmove                 the engine moves, and assumes its move is played
+//	result <win|loss|draw>  the episode is over, in the engine's perspective
+//	quit                    the session is over; the engine answers and exits
+//
+// Rows and columns count from 0. The host sends newgame, board and symbol before any other
+// command of an episode, and moved or genmove only in the turn of the side that moves.
+const (
+	engineOK    = "ok"
+	engineMove  = "move"
+	engineError = "error"
+)
+
+// episode results in the engine's perspective
+const (
+	engineWin  = "win"
+	engineLoss = "loss"
+	engineDraw = "draw"
+)
+
+// result of the episode in the perspective of the player playing symbol
+func engineResult(env environment, symbol string) string {
+	switch env.winner {
+	case "":
+		return engineDraw
+	case symbol:
+		return engineWin
+	}
+	return engineLoss
+}
+
+// engine plays the player through the engine protocol, for an outside host
+type engine struct {
+	p       *player
+	env     environment
+	spec    boardSpec
+	started bool // a move of the episode is played, and its result is not known yet
+}
+
+// speak the engine protocol with the player until the host quits or closes the input; the
+// player learns from the episodes and exports what it has learnt at the end
+func runEngine(p *player, r io.Reader, w io.Writer) error {
+	e := engine{p: p, spec: defaultSpec}
+	e.env.initializeEnvironment(e.spec)
+	p.symbol = "x"
+	p.agent.startSession(false)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" {
+			break
+		}
+		reply, err := e.command(fields)
+		if err != nil {
+			reply = engineError + " " + err.Error()
+		}
+		if _, err := fmt.Fprintln(w, reply); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	err := p.agent.export(p)
+	if err == nil {
+		_, err = fmt.Fprintln(w, engineOK)
+	}
+	return err
+}
+
+// run a command of the host and return the reply
+func (e *engine) command(fields []string) (string, error) {
+	switch fields[0] {
+	case "newgame", "board", "symbol":
+		if e.started {
+			return "", fmt.Errorf("the episode is going on; send its result first")
+		}
+	case "result":
+		if !e.started {
+			return "", fmt.Errorf("no episode is going on")
+		}
+	}
+	switch fields[0] {
+	case "newgame":
+		if len(fields) == 1 {
+			e.env.initializeEnvironment(e.spec)
+			return engineOK, nil
+		}
+	case "board":
+		var spec boardSpec
+		if len(fields) == 4 {
+			if _, err := fmt.Sscan(strings.Join(fields[1:], " "), &spec.rows, &spec.cols, &spec.k); err != nil {
+				return "", fmt.Errorf("invalid board %v", strings.Join(fields[1:], " "))
+			}
+			if err := spec.validate(); err != nil {
+				return "", err
+			}
+			e.spec = spec
+			e.env.initializeEnvironment(spec)
+			return engineOK, nil
+		}
+	case "symbol":
+		if len(fields) == 2 && (fields[1] == "x" || fields[1] == "o") {
+			e.p.symbol = fields[1]
+			return engineOK, nil
+		}
+	case "moved":
+		if len(fields) == 3 {
+			loc, err := parseEngineMove(fields[1:], e.env)
+			if err != nil {
+				return "", err
+			}
+			if nextSymbol(e.env.board) == e.p.symbol {
+				return "", fmt.Errorf("it's the engine's turn")
+			}
+			e.play(loc, opponentSymbol(e.p.symbol))
+			return engineOK, nil
+		}
+	case "genmove":
+		if len(fields) == 1 {
+			if e.env.gameOver {
+				return "", fmt.Errorf("the episode is over")
+			}
+			if nextSymbol(e.env.board) != e.p.symbol {
+				return "", fmt.Errorf("it's the opponent's turn")
+			}
+			loc := e.p.playerActs(e.env)
+			e.play(loc, e.p.symbol)
+			return fmt.Sprintf("%v %v %v", engineMove, loc[0], loc[1]), nil
+		}
+	case "result":
+		if len(fields) == 2 && (fields[1] == engineWin || fields[1] == engineLoss || fields[1] == engineDraw) {
+			// the host's word is final, e.g. for an episode lost on time
+			e.env.gameOver = true
+			switch fields[1] {
+			case engineWin:
+				e.env.winner = e.p.symbol
+			case engineLoss:
+				e.env.winner = opponentSymbol(e.p.symbol)
+			default:
+				e.env.winner = ""
+			}
+			e.p.updatePlayerRecord(e.env)
+			e.started = false
+			return engineOK, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown command %v", fields[0])
+	}
+	return "", fmt.Errorf("invalid arguments of %v", fields[0])
+}
+
+// make the move on the board and let the player see it
+func (e *engine) play(loc location, symbol string) {
+	e.started = true
+	e.env.updateGameStatus(loc, symbol)
+	e.p.agent.observe(e.env, e.p.symbol)
+	return
+}
+
+// parse the location of a move, which must be an empty location of the board
+func parseEngineMove(fields []string, env environment) (location, error) {
+	var loc location
+	if len(fields) != 2 {
+		return loc, fmt.Errorf("invalid move %v", strings.Join(fields, " "))
+	}
+	if _, err := fmt.Sscan(fields[0]+" "+fields[1], &loc[0], &loc[1]); err != nil {
+		return loc, fmt.Errorf("invalid move %v", strings.Join(fields, " "))
+	}
+	if env.gameOver {
+		return loc, fmt.Errorf("the episode is over")
+	}
+	if loc[0] < 0 || loc[0] >= env.spec.rows || loc[1] < 0 || loc[1] >= env.spec.cols || env.board[loc[0]][loc[1]] != "" {
+		return loc, fmt.Errorf("%v %v is not an empty location of the %v board", loc[0], loc[1], env.spec)
+	}
+	return loc, nil
+}
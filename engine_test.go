@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// the engine refuses commands out of turn or out of the episode
+func TestEngineTurns(t *testing.T) {
+	e := engine{p: &player{name: "e", agent: &stubAgent{}}, spec: defaultSpec}
+	e.env.initializeEnvironment(e.spec)
+	steps := []struct {
+		command string
+		ok      bool
+	}{
+		{"result win", false}, // no episode is going on
+		{"symbol o", true},
+		{"genmove", false}, // x moves first
+		{"moved 1 1", true},
+		{"moved 0 0", false}, // the engine's turn
+		{"genmove", true},
+		{"newgame", false}, // the episode is going on
+		{"genmove", false}, // the opponent's turn
+		{"result draw", true},
+		{"newgame", true},
+	}
+	for _, s := range steps {
+		_, err := e.command(strings.Fields(s.command))
+		if (err == nil) != s.ok {
+			t.Errorf("%q: got error %v, want ok %v", s.command, err, s.ok)
+		}
+	}
+}
+
+// external players are only declared in a config file, not through the API
+func TestValidateAPIPlayerExternal(t *testing.T) {
+	if err := validateAPIPlayer(playerConfig{Name: "e", Being: "external"}); err == nil {
+		t.Errorf("external player is accepted")
+	}
+}
+
+// the episode of an engine that fails is abandoned: nobody learns from it and it's not
+// counted, and the session ends with the failure
+func TestSessionEngineFailure(t *testing.T) {
+	a := &stubAgent{}
+	// the engine starts the episode, and exits at the first move of its opponent
+	ex := newExternal("e", []string{"sh", "-c", "for i in 1 2 3; do read l; echo ok; done"})
+	ps, opts := stubSession(a, ex, 3)
+	if _, err := runSession(ps, opts); err == nil || !strings.Contains(err.Error(), "p2 failed") {
+		t.Errorf("got error %v, want the failure of the engine", err)
+	}
+	if n := ps[0].wins + ps[0].draws + ps[0].losses; n != 0 || a.learnt != 0 {
+		t.Errorf("%v episodes counted and %v learnt, want none", n, a.learnt)
+	}
+}
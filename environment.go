@@ -2,17 +2,59 @@ package main
 
 import (
 	"fmt"
-	"math"
+	"strings"
 )
 
 type location [2]int
 
 type board [][]string
 
+// boardSpec defines an m,n,k-game: a rows x cols board won by k symbols in a row
+type boardSpec struct {
+	rows int // number of rows of the board
+	cols int // number of columns of the board
+	k    int // number of symbols in a row to win
+}
+
 type environment struct {
+	spec     boardSpec
 	board    board
 	winner   string
 	gameOver bool
+	lastMove location // location of the latest move, if any
+}
+
+// largest number of rows or columns, which the header of a state key holds in a byte
+const maxBoardSize = 255
+
+// the classic 3x3 tic-tac-toe
+var defaultSpec = boardSpec{rows: defaultRows, cols: defaultCols, k: defaultWinLength}
+
+// check that the board can be built and the game can be won
+func (bs boardSpec) validate() error {
+	if bs.rows < 1 || bs.cols < 1 {
+		return fmt.Errorf("invalid board size %vx%v", bs.rows, bs.cols)
+	}
+	if bs.rows > maxBoardSize || bs.cols > maxBoardSize {
+		return fmt.Errorf("board %vx%v is larger than %vx%v", bs.rows, bs.cols, maxBoardSize, maxBoardSize)
+	}
+	if bs.k < 1 || (bs.k > bs.rows && bs.k > bs.cols) {
+		return fmt.Errorf("win length %v does not fit on a %vx%v board", bs.k, bs.rows, bs.cols)
+	}
+	return nil
+}
+
+// parse a board spec written as "rows,cols,k"
+func parseBoardSpec(s string) (boardSpec, error) {
+	var bs boardSpec
+	if _, err := fmt.Sscanf(s, "%d,%d,%d", &bs.rows, &bs.cols, &bs.k); err != nil {
+		return bs, fmt.Errorf("invalid board %q, expected rows,cols,k", s)
+	}
+	return bs, bs.validate()
+}
+
+func (bs boardSpec) String() string {
+	return fmt.Sprintf("%vx%v (%v in a row)", bs.rows, bs.cols, bs.k)
 }
 
 // report the summary of the episode
@@ -34,90 +76,25 @@ func (env *environment) summarizeEpisode(p1, p2 *player) {
 }
 
 // initialize environment
-func (env *environment) initializeEnvironment() {
-	board := make(board, boardSize)
+func (env *environment) initializeEnvironment(spec boardSpec) {
+	board := make(board, spec.rows)
 	for irow := range board {
-		board[irow] = make([]string, boardSize)
+		board[irow] = make([]string, spec.cols)
 	}
+	env.spec = spec
 	env.board = board
 	env.winner = ""
 	env.gameOver = false
 	return
 }
 
-// encode the game board into an integer (state id)
-// NOTE: For each player, each location's status is viewed only as occupied either by him/herself or
-//       by the opponent, regardless of the actual symbol ("x" or "o") there.
-// NOTE: For the same board and the same user, the player plays next or the opponent plays next makes
-//       different states.
-func boardToState(b *board, symbol string) int64 {
-	var k, h, v, r int64
-	// encode board
-	for _, row := range *b {
-		for _, element := range row {
-			if element == symbol { // occupied by current player
-				v = 0
-			} else if element == "" { // empty
-				v = 1
-			} else { // occupied by opponent
-				v = 2
-			}
-			h += int64(math.Pow(3, float64(k))) * v
-			k++
-		}
-	}
-	// encode player symbol
-	if symbol == "x" {
-		r = 0
-	} else {
-		r = 1
-	}
-	h += int64(math.Pow(3, float64(k))) * r
-	return h
-}
-
-// decode the state id to reconstruct the board in player's perspective
-func stateToBoard(h int64) (board, string) {
-	var symbol string
-	k := boardSize * boardSize
-	// decode player symbol
-	base := int64(math.Pow(3, float64(k)))
-	v := h / base
-	if v == 0 {
-		symbol = "x"
-	} else {
-		symbol = "o"
-	}
-	h -= v * base
-	k--
-	// decode board
-	b := make(board, boardSize)
-	for irow := boardSize - 1; irow >= 0; irow-- {
-		r := make([]string, boardSize)
-		for ielement := boardSize - 1; ielement >= 0; ielement-- {
-			base = int64(math.Pow(3, float64(k)))
-			v = h / base
-			if v == 0 {
-				r[ielement] = "P" // the player
-			} else if v == 1 {
-				r[ielement] = "" // empty
-			} else {
-				r[ielement] = "-" // the opponent
-			}
-			h -= v * base
-			k--
-		}
-		b[irow] = r
-	}
-	return b, symbol
-}
-
 // examine the board following a move and updates the winner and the game-over
 func (env *environment) updateGameStatus(loc location, symbol string) {
 	// add new move on the board
 	env.board[loc[0]][loc[1]] = symbol
+	env.lastMove = loc
 	// update status
-	env.winner = getWinner(env.board)
+	env.winner = getWinner(env.board, env.spec.k)
 	if env.winner != "" || getEmpties(env.board) == 0 {
 		env.gameOver = true
 	} else {
@@ -146,8 +123,12 @@ func padSymbol(s string) string {
 // print the board with players on it
 func printBoard(b *board, toScreen bool) string {
 	var content string
+	var line string // horizontal border, 6 characters per column
+	if len(*b) > 0 {
+		line = strings.Repeat("-", 6*len((*b)[0])+1) + " \n"
+	}
 	for _, row := range *b {
-		content += "------------------- \n"
+		content += line
 		//fmt.Println("-------------------")
 		rowPrint := "|"
 		for _, element := range row {
@@ -157,7 +138,7 @@ func printBoard(b *board, toScreen bool) string {
 		content += "\n"
 		//fmt.Println(rowPrint)
 	}
-	content += "------------------- \n"
+	content += line
 	//fmt.Println("-------------------")
 	if toScreen {
 		fmt.Print(content)
@@ -165,63 +146,66 @@ func printBoard(b *board, toScreen bool) string {
 	return content
 }
 
-// check whether all elements in a string array are equal to a certain string
-func rowFilled(array []string, s string) bool {
-	for _, element := range array {
-		if element != s {
+// check whether the k locations starting from loc in direction d are all occupied by symbol s
+func lineFilled(b board, loc location, d [2]int, k int, s string) bool {
+	for i := 0; i < k; i++ {
+		irow, icol := loc[0]+i*d[0], loc[1]+i*d[1]
+		if irow < 0 || irow >= len(b) || icol < 0 || icol >= len(b[irow]) {
+			return false
+		}
+		if b[irow][icol] != s {
 			return false
 		}
 	}
 	return true
 }
 
-// check the current board and find the winner
-func getWinner(b board) string {
-	symbols := [2]string{"x", "o"} // player symbols on the board
-
-	// rows
-	for _, row := range b {
-		for _, p := range symbols {
-			if rowFilled(row, p) {
-				return p
+// check the current board and find the winner, who has k symbols in a row
+func getWinner(b board, k int) string {
+	// rows, columns, top-left to bottom-right, top-right to bottom-left
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for irow, row := range b {
+		for icol, element := range row {
+			if element == "" {
+				continue
+			}
+			for _, d := range directions {
+				if lineFilled(b, location{irow, icol}, d, k, element) {
+					return element
+				}
 			}
 		}
 	}
-	// columns
-	for icol := range b[0] {
-		// collection is an array composed by elements of this column
-		collection := []string{}
-		for irow := range b {
-			collection = append(collection, b[irow][icol])
-		}
-		for _, p := range symbols {
-			if rowFilled(collection, p) {
-				return p
+	// no winner found
+	return ""
+}
+
+// check whether the symbol at loc, just placed there, completes k symbols in a row
+func isWinningMove(b board, loc location, k int) bool {
+	s := b[loc[0]][loc[1]]
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for _, d := range directions {
+		n := 1
+		for _, sign := range [2]int{1, -1} {
+			irow, icol := loc[0]+sign*d[0], loc[1]+sign*d[1]
+			for irow >= 0 && irow < len(b) && icol >= 0 && icol < len(b[irow]) && b[irow][icol] == s {
+				n++
+				irow, icol = irow+sign*d[0], icol+sign*d[1]
 			}
 		}
-	}
-	// top-left to bottom-right
-	var targetArray []string
-	for i := range b {
-		targetArray = append(targetArray, b[i][i])
-	}
-	for _, p := range symbols {
-		if rowFilled(targetArray, p) {
-			return p
+		if n >= k {
+			return true
 		}
 	}
-	// top-right to bottom-left
-	targetArray = []string{}
-	for i := range b {
-		targetArray = append(targetArray, b[i][boardSize-1-i])
-	}
-	for _, p := range symbols {
-		if rowFilled(targetArray, p) {
-			return p
-		}
+	return false
+}
+
+// the other player's symbol
+func opponentSymbol(s string) string {
+	if s == "x" {
+		return "o"
 	}
-	// no winner found
-	return ""
+	return "x"
 }
 
 // check number of empty spots
@@ -237,6 +221,24 @@ func getEmpties(b board) int {
 	return n
 }
 
+// symbol of the player who moves next on the board; "x" moves first
+func nextSymbol(b board) string {
+	var nx, no int
+	for _, row := range b {
+		for _, element := range row {
+			if element == "x" {
+				nx++
+			} else if element == "o" {
+				no++
+			}
+		}
+	}
+	if nx > no {
+		return "o"
+	}
+	return "x"
+}
+
 // get reward for a certain player by knowing the winner
 func getReward(w, s string) float64 {
 	if w == s { // this player wins
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// board from rows of "x", "o" and "." for an empty location
+func parseBoard(rows ...string) board {
+	b := make(board, len(rows))
+	for irow, row := range rows {
+		b[irow] = make([]string, len(row))
+		for icol, c := range row {
+			if c != '.' {
+				b[irow][icol] = string(c)
+			}
+		}
+	}
+	return b
+}
+
+func TestGetWinner(t *testing.T) {
+	tests := []struct {
+		rows   []string
+		k      int
+		winner string
+	}{
+		{[]string{"...", "...", "..."}, 3, ""},
+		{[]string{"xxx", "oo.", "..."}, 3, "x"},
+		{[]string{"xo.", "xo.", ".o."}, 3, "o"},
+		{[]string{"x.o", ".xo", "..x"}, 3, "x"},
+		{[]string{"x.o", ".o.", "ox."}, 3, "o"},
+		{[]string{"xox", "xoo", "oxx"}, 3, ""},
+		// m,n,k boards: a line of k wins on a board larger than k
+		{[]string{".....", ".xxx.", ".oo..", "....."}, 3, "x"},
+		{[]string{".....", ".xxx.", ".ooo.", "....."}, 4, ""},
+		{[]string{"......o", ".....o.", "....o..", "...o...", "xxx...."}, 4, "o"},
+		{[]string{"x..", ".x.", "..x", "...", "..."}, 3, "x"},
+		{[]string{"o", "o", "o", "x", "x"}, 3, "o"},
+		{[]string{"..x.."}, 1, "x"},
+		{[]string{"xx.xx"}, 3, ""},
+	}
+	for _, tt := range tests {
+		if w := getWinner(parseBoard(tt.rows...), tt.k); w != tt.winner {
+			t.Errorf("getWinner(%v, k=%v) = %q, want %q", strings.Join(tt.rows, "/"), tt.k, w, tt.winner)
+		}
+	}
+}
+
+// isWinningMove agrees with getWinner on the move that completes a line
+func TestIsWinningMove(t *testing.T) {
+	b := parseBoard("......o", ".....o.", "....o..", "...o...", "xxx....")
+	if !isWinningMove(b, location{2, 4}, 4) || isWinningMove(b, location{4, 0}, 4) {
+		t.Errorf("isWinningMove disagrees with getWinner")
+	}
+}
+
+func TestBoardSpecValidate(t *testing.T) {
+	valid := []boardSpec{{3, 3, 3}, {1, 1, 1}, {4, 5, 4}, {2, 7, 5}, {maxBoardSize, maxBoardSize, 5}, {maxBoardSize, 1, maxBoardSize}}
+	for _, bs := range valid {
+		if err := bs.validate(); err != nil {
+			t.Errorf("%v: %v", bs, err)
+		}
+	}
+	// a side over 255 would be truncated in the header of a state key
+	invalid := []boardSpec{{0, 3, 1}, {3, -1, 1}, {3, 3, 0}, {3, 3, 4}, {maxBoardSize + 1, 3, 3}, {3, maxBoardSize + 1, 3}}
+	for _, bs := range invalid {
+		if err := bs.validate(); err == nil {
+			t.Errorf("%vx%v k=%v is valid", bs.rows, bs.cols, bs.k)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// exploration policies of robots
+const (
+	epsGreedy  = "eps"        // a random move with probability eps, otherwise the best move
+	boltzmann  = "softmax"    // softmax:T, a move with probability proportional to exp(gain/T)
+	ucb1       = "ucb"        // ucb:C, the best move by gain plus C*sqrt(ln N/n), n the times it's been tried
+	optimistic = "optimistic" // optimistic:V, the best move, with unknown states or actions valued V
+)
+
+// default parameters of the exploration policies
+const (
+	defaultTemperature = 0.1
+	defaultUCBConstant = math.Sqrt2
+	defaultOptimism    = winReward
+)
+
+// exploration is the policy by which a robot chooses its moves from their gains
+type exploration struct {
+	policy string  // exploration policy, epsGreedy if empty
+	param  float64 // temperature, exploration constant or initial value, by policy
+}
+
+// text form of the exploration, e.g. "eps" or "softmax:0.1"
+func (x exploration) String() string {
+	if x.policy == "" || x.policy == epsGreedy {
+		return epsGreedy
+	}
+	return x.policy + ":" + strconv.FormatFloat(x.param, 'g', -1, 64)
+}
+
+// parse the text form of an exploration; a policy without parameter takes the default one
+func parseExploration(text string) (exploration, error) {
+	fields := strings.SplitN(text, ":", 2)
+	x := exploration{policy: fields[0]}
+	switch x.policy {
+	case "", epsGreedy:
+		if len(fields) > 1 {
+			return x, fmt.Errorf("exploration %q takes no parameter", text)
+		}
+		x.policy = epsGreedy
+		return x, nil
+	case boltzmann:
+		x.param = defaultTemperature
+	case ucb1:
+		x.param = defaultUCBConstant
+	case optimistic:
+		x.param = defaultOptimism
+	default:
+		return x, fmt.Errorf("unknown exploration %q", text)
+	}
+	if len(fields) > 1 {
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return x, fmt.Errorf("invalid exploration %q", text)
+		}
+		x.param = v
+	}
+	if x.policy == boltzmann && x.param <= 0 {
+		return x, fmt.Errorf("exploration %q needs a positive temperature", text)
+	}
+	if x.policy == ucb1 && x.param < 0 {
+		return x, fmt.Errorf("exploration %q needs a non-negative constant", text)
+	}
+	return x, nil
+}
+
+// an exploration is read from its text form
+func (x *exploration) UnmarshalJSON(d []byte) error {
+	var text string
+	if err := json.Unmarshal(d, &text); err != nil {
+		return fmt.Errorf("an exploration is a string, got %s", d)
+	}
+	parsed, err := parseExploration(text)
+	if err != nil {
+		return err
+	}
+	*x = parsed
+	return nil
+}
+
+// value of an unknown state or action; optimistic, or else the default value
+func (x exploration) unknownValue(rng *rand.Rand) float64 {
+	if x.policy == optimistic {
+		return x.param
+	}
+	return defaultValue(rng)
+}
+
+// probabilities of choosing each of the moves, given their gains and how many times each has
+// been tried; the first of equally good moves counts as the best
+func (x exploration) probabilities(gains []float64, counts []uint, eps float64) []float64 {
+	n := len(gains)
+	probs := make([]float64, n)
+	switch x.policy {
+	case boltzmann:
+		max := gains[argmax(gains)]
+		var sum float64
+		for i, gain := range gains {
+			probs[i] = math.Exp((gain - max) / x.param)
+			sum += probs[i]
+		}
+		for i := range probs {
+			probs[i] /= sum
+		}
+	case ucb1:
+		var total uint
+		for _, count := range counts {
+			total += count
+		}
+		scores := make([]float64, n)
+		for i, gain := range gains {
+			if counts[i] == 0 {
+				scores[i] = math.Inf(1) // untried moves go first
+				continue
+			}
+			scores[i] = gain + x.param*math.Sqrt(math.Log(float64(total))/float64(counts[i]))
+		}
+		probs[argmax(scores)] = 1
+	case optimistic:
+		probs[argmax(gains)] = 1
+	default:
+		for i := range probs {
+			probs[i] = eps / float64(n)
+		}
+		probs[argmax(gains)] += 1 - eps
+	}
+	return probs
+}
+
+// lay the probability of each move on a copy of the board
+func probabilityBoard(b board, moves []location, probs []float64) board {
+	plan := make(board, len(b))
+	for irow, row := range b {
+		plan[irow] = append([]string(nil), row...)
+	}
+	for i, loc := range moves {
+		plan[loc[0]][loc[1]] = strconv.FormatFloat(probs[i], 'f', 2, 64)
+	}
+	return plan
+}
+
+// index of the first largest value
+func argmax(values []float64) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// draw an index with the given probabilities
+func sample(probs []float64, rng *rand.Rand) int {
+	u := rng.Float64()
+	for i, p := range probs {
+		if u < p {
+			return i
+		}
+		u -= p
+	}
+	// rounding errors; fall back on the last move with a chance
+	for i := len(probs) - 1; i > 0; i-- {
+		if probs[i] > 0 {
+			return i
+		}
+	}
+	return 0
+}
@@ -7,11 +7,42 @@ import (
 	"log"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 )
 
-// write state values of the player to a csv file
-func exportValues(name string, values stateValues) {
+// states of the values in order of their keys, so exports are reproducible
+func sortedStates(values stateValues) []stateKey {
+	states := make([]stateKey, 0, len(values))
+	for state := range values {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	return states
+}
+
+// actions of the action values in order of their states and locations
+func sortedActions(qvalues actionValues) []action {
+	actions := make([]action, 0, len(qvalues))
+	for a := range qvalues {
+		actions = append(actions, a)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		a, b := actions[i], actions[j]
+		if a.state != b.state {
+			return a.state < b.state
+		}
+		if a.loc[0] != b.loc[0] {
+			return a.loc[0] < b.loc[0]
+		}
+		return a.loc[1] < b.loc[1]
+	})
+	return actions
+}
+
+// write state values of the player to a csv file; for a robot learning on canonical states,
+// also report how many raw states they stand for, i.e. all their orientations
+func exportValues(name string, values stateValues, sym bool) {
 	filename := name + ".values.csv"
 	file, err := os.Create(filename)
 	if err != nil {
@@ -22,14 +53,48 @@ func exportValues(name string, values stateValues) {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	for state, value := range values {
-		row := []string{strconv.FormatInt(state, 10), strconv.FormatFloat(value, 'g', 5, 64)}
+	for _, state := range sortedStates(values) {
+		row := []string{state.String(), strconv.FormatFloat(values[state], 'g', 5, 64)}
 		err := writer.Write(row)
 		if err != nil {
 			log.Fatal("Cannot write to file", err)
 		}
 	}
 	fmt.Printf("%v has %v state-values, saved into %v \n", name, len(values), filename)
+	if sym {
+		var nraw int
+		for state := range values {
+			nraw += len(state.orbit())
+		}
+		fmt.Printf("%v's %v canonical states stand for up to %v raw states by symmetry, whether or not they were seen \n", name, len(values), nraw)
+	}
+	return
+}
+
+// write action values of the player to a csv file, one row per state and location
+func exportQValues(name string, qvalues actionValues) {
+	filename := name + ".qvalues.csv"
+	file, err := os.Create(filename)
+	if err != nil {
+		log.Fatal("Cannot create file", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	for _, a := range sortedActions(qvalues) {
+		row := []string{
+			a.state.String(),
+			strconv.Itoa(a.loc[0]),
+			strconv.Itoa(a.loc[1]),
+			strconv.FormatFloat(qvalues[a], 'g', 5, 64)}
+		err := writer.Write(row)
+		if err != nil {
+			log.Fatal("Cannot write to file", err)
+		}
+	}
+	fmt.Printf("%v has %v action-values, saved into %v \n", name, len(qvalues), filename)
 	return
 }
 
@@ -47,15 +112,21 @@ func exportValueHistory(name string, vhist stateValueHistory) {
 	filename2 := name + ".demo_states.txt"
 
 	var s string // the "print out" of the board
-	for state, valueHistory := range vhist {
+	states := make([]stateKey, 0, len(vhist))
+	for state := range vhist {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+	for _, state := range states {
+		valueHistory := vhist[state]
 
 		b, sym := stateToBoard(state)
-		s = s + strconv.FormatInt(state, 10) + "\n" + "player plays " + sym + "\n" + printBoard(&b, false) + "\n"
+		s = s + state.String() + "\n" + "player plays " + sym + "\n" + printBoard(&b, false) + "\n"
 
 		for time, value := range valueHistory {
 			if math.Mod(float64(time), float64(nPrintHistory)) == 0.0 {
 				row := []string{
-					strconv.FormatInt(state, 10),
+					state.String(),
 					strconv.Itoa(time),
 					strconv.FormatFloat(value, 'g', 5, 64)}
 				err := writer.Write(row)
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const engineTimeout = time.Minute // longest wait for a reply of an engine, or for it to exit after quit
+
+// engineLine is a line of the engine's stdout, or the error that ends it
+type engineLine struct {
+	text string
+	err  error
+}
+
+// external is an agent outside GoTick, e.g. written in Python or Rust, run as a subprocess
+// speaking the engine protocol on its stdin and stdout. The engine is started at the start of
+// each session and quits at its end, so it can save what it has learnt on its own. An engine
+// that fails is stopped, which abandons its episode and ends its session.
+type external struct {
+	name    string   // name of the player, for printing
+	command []string // command line of the engine
+	verb    bool     // verbose, printing the exchange with the engine
+	cmd     *exec.Cmd
+	in      io.WriteCloser  // the engine's stdin
+	out     chan engineLine // lines of the engine's stdout, read in the background
+	stop    chan struct{}   // closed to stop reading the engine's stdout
+	playing bool            // the engine is told of the episode going on
+	err     error           // failure of the engine in the session, which ends the session
+}
+
+func newExternal(name string, command []string) *external {
+	return &external{name: name, command: command}
+}
+
+func (ex *external) kind() string {
+	return "external"
+}
+
+// start the engine
+func (ex *external) startSession(verb bool) {
+	ex.verb, ex.playing, ex.err = verb, false, nil
+	if ex.cmd != nil {
+		return
+	}
+	cmd := exec.Command(ex.command[0], ex.command[1:]...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	var out io.ReadCloser
+	if err == nil {
+		out, err = cmd.StdoutPipe()
+	}
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		ex.fail(fmt.Errorf("cannot start the engine: %v", err))
+		return
+	}
+	ex.cmd, ex.in = cmd, in
+	ex.out, ex.stop = make(chan engineLine), make(chan struct{})
+	go readEngine(bufio.NewScanner(out), ex.out, ex.stop)
+	return
+}
+
+// read the lines of the engine's stdout into the channel until it ends or is stopped, so a
+// reply can be waited for with a timeout
+func readEngine(scanner *bufio.Scanner, lines chan<- engineLine, stop <-chan struct{}) {
+	for {
+		var line engineLine
+		if scanner.Scan() {
+			line.text = scanner.Text()
+		} else if line.err = scanner.Err(); line.err == nil {
+			line.err = io.ErrUnexpectedEOF
+		}
+		select {
+		case lines <- line:
+		case <-stop:
+			return
+		}
+		if line.err != nil {
+			return
+		}
+	}
+}
+
+func (ex *external) failure() error {
+	return ex.err
+}
+
+// record the failure of the engine and stop it, as it can't be trusted to play on
+func (ex *external) fail(err error) {
+	if ex.err == nil {
+		ex.err = err
+	}
+	if ex.cmd != nil {
+		ex.in.Close()
+		ex.cmd.Process.Kill()
+		ex.wait()
+	}
+	return
+}
+
+// wait for the engine to exit, and kill it if it doesn't in time
+func (ex *external) wait() error {
+	close(ex.stop)
+	exited := make(chan error, 1)
+	go func(cmd *exec.Cmd) { exited <- cmd.Wait() }(ex.cmd)
+	var err error
+	select {
+	case err = <-exited:
+	case <-time.After(engineTimeout):
+		ex.cmd.Process.Kill()
+		err = fmt.Errorf("did not exit within %v, killed: %v", engineTimeout, <-exited)
+	}
+	ex.cmd, ex.in, ex.out, ex.stop = nil, nil, nil, nil
+	return err
+}
+
+// tell the engine of the episode, the first time the agent acts or observes in it
+func (ex *external) begin(env environment, symbol string) {
+	if ex.playing {
+		return
+	}
+	ex.send("newgame")
+	ex.send(fmt.Sprintf("board %v %v %v", env.spec.rows, env.spec.cols, env.spec.k))
+	ex.send("symbol " + symbol)
+	ex.playing = true
+	return
+}
+
+// ask the engine for its move
+func (ex *external) act(env environment, symbol string) location {
+	ex.begin(env, symbol)
+	reply := ex.send("genmove")
+	if ex.err != nil {
+		return location{} // not played, as the episode is abandoned
+	}
+	fields := strings.Fields(reply)
+	if len(fields) == 0 || fields[0] != engineMove {
+		ex.fail(fmt.Errorf("answered %q to genmove", reply))
+		return location{}
+	}
+	loc, err := parseEngineMove(fields[1:], env)
+	if err != nil {
+		ex.fail(fmt.Errorf("made an invalid move: %v", err))
+		return location{}
+	}
+	return loc
+}
+
+// tell the engine of the opponent's moves
+func (ex *external) observe(env environment, symbol string) {
+	ex.begin(env, symbol)
+	loc := env.lastMove
+	if env.board[loc[0]][loc[1]] != symbol {
+		ex.send(fmt.Sprintf("moved %v %v", loc[0], loc[1]))
+	}
+	return
+}
+
+// tell the engine of the result of the episode
+func (ex *external) learn(env environment, symbol string) {
+	ex.begin(env, symbol)
+	ex.send("result " + engineResult(env, symbol))
+	ex.playing = false
+	return
+}
+
+// forget the abandoned episode; the engine is told of the next episode as usual
+func (ex *external) forget() {
+	ex.playing = false
+	return
+}
+
+// let the engine quit; an engine stopped at its failure has nothing to add to the failure
+// that ends the session
+func (ex *external) export(p *player) error {
+	if ex.cmd == nil {
+		return nil
+	}
+	ex.send("quit")
+	if ex.cmd == nil { // failed to quit
+		return ex.err
+	}
+	ex.in.Close()
+	if err := ex.wait(); err != nil {
+		return fmt.Errorf("engine %v: %v", ex.name, err)
+	}
+	return nil
+}
+
+// send a command to the engine and return its reply; the engine fails if it can't be reached,
+// doesn't reply in time or reports an error, and is sent nothing after its failure
+func (ex *external) send(command string) string {
+	if ex.err != nil {
+		return ""
+	}
+	if ex.verb {
+		fmt.Printf("%v < %v \n", ex.name, command)
+	}
+	if _, err := fmt.Fprintln(ex.in, command); err != nil {
+		ex.fail(fmt.Errorf("cannot send %q: %v", command, err))
+		return ""
+	}
+	var line engineLine
+	select {
+	case line = <-ex.out:
+	case <-time.After(engineTimeout):
+		line.err = fmt.Errorf("no reply within %v", engineTimeout)
+	}
+	if line.err != nil {
+		ex.fail(fmt.Errorf("cannot read the reply to %q: %v", command, line.err))
+		return ""
+	}
+	reply := strings.TrimSpace(line.text)
+	if ex.verb {
+		fmt.Printf("%v > %v \n", ex.name, reply)
+	}
+	if strings.HasPrefix(reply, engineError) {
+		ex.fail(fmt.Errorf("failed on %q: %v", command, strings.TrimSpace(strings.TrimPrefix(reply, engineError))))
+		return ""
+	}
+	return reply
+}
@@ -4,9 +4,10 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 )
 
-func createSessions(players []player) {
+func createSessions(players []player, ratings *ratingBook, games *gameLog) {
 	for {
 
 		// user input
@@ -28,14 +29,26 @@ func createSessions(players []player) {
 			fmt.Printf("#%v %v \n", i, p.name)
 		}
 		var i1, i2, n int
-		if len(players) > 2 { // more than 2 available players, choose 2
+		var t tournament
+		if len(players) > 2 { // more than 2 available players, choose 2 or all of them
 			for {
-				fmt.Printf("pick two players (# #): ")
+				fmt.Printf("pick two players (# #) / click enter to run a tournament of all players: ")
 				_, err := fmt.Scanf("%d%d", &i1, &i2)
-				if err == nil {
+				if err != nil {
+					t.format = promptTournamentFormat()
+					break
+				}
+				if i1 != i2 && i1 >= 0 && i2 >= 0 && i1 < len(players) && i2 < len(players) {
 					break
 				}
 			}
+			if t.format == swiss {
+				fmt.Printf("how many rounds / click enter to use the default (%v): ", defaultSwissRounds(len(players)))
+				_, err := fmt.Scanf("%d", &t.rounds)
+				if err != nil || t.rounds < 1 {
+					t.rounds = defaultSwissRounds(len(players))
+				}
+			}
 		} else {
 			i1, i2 = 0, 1
 		}
@@ -48,66 +61,204 @@ func createSessions(players []player) {
 			}
 		}
 
-		// run session
-		fmt.Printf("*** Session starts: %v and %v play %v episodes *** \n", players[i1].name, players[i2].name, n)
-		runSession(&playerPair{players[i1], players[i2]}, n)
+		// board size and win length
+		var spec boardSpec
+		for {
+			fmt.Printf("board (rows cols k) / click enter to use default values (%v %v %v): ", defaultRows, defaultCols, defaultWinLength)
+			_, err := fmt.Scanf("%d%d%d", &spec.rows, &spec.cols, &spec.k)
+			if err != nil {
+				spec = defaultSpec
+				fmt.Printf("use default board \n")
+			}
+			err = spec.validate()
+			if err == nil {
+				break
+			}
+			fmt.Printf("%v \n", err)
+		}
+
+		// verbosity of robots playing a human
+		v := false
+		ps := &playerPair{&players[i1], &players[i2]}
+		if ps.hasHuman() || (t.format != "" && hasHuman(players)) {
+			for {
+				fmt.Printf("set robot to verbose? (t/f): ")
+				_, err := fmt.Scanf("%t", &v)
+				if err == nil {
+					break
+				}
+			}
+		}
+
+		// run tournament or session
+		opts := sessionOptions{episodes: n, spec: spec, verbose: v, first: randomFirst, ratings: ratings, rng: newRand(), games: games}
+		if t.format != "" {
+			t.opts = opts
+			all := make([]*player, len(players))
+			for i := range players {
+				all[i] = &players[i]
+			}
+			runTournament(all, t) // a failure is reported by its session
+			continue
+		}
+		runSession(ps, opts) // a failure is reported by the session
 	}
 
 	return
 }
 
-func runSession(ps *playerPair, nEpisodes int) {
+// the first player of each episode is drawn at random
+const randomFirst = -1
+
+// sessionOptions are the settings of a session beside its players
+type sessionOptions struct {
+	episodes int              // number of episodes
+	spec     boardSpec        // board of the episodes
+	verbose  bool             // agents are verbose
+	first    int              // index of the player who always plays first, or randomFirst
+	ratings  *ratingBook      // ratings updated by the session; not rated if nil
+	rng      *rand.Rand       // random source of the session, not shared with other sessions
+	games    *gameLog         // log the episodes are recorded into; not recorded if nil
+	progress *sessionProgress // progress updated after each episode; not tracked if nil
+	stop     <-chan struct{}  // closed to stop the session after the current episode; never stopped if nil
+}
+
+// check whether the session is asked to stop
+func (opts sessionOptions) stopped() bool {
+	select {
+	case <-opts.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// sessionResult is the record of a session, in the order of the player pair
+type sessionResult struct {
+	wins  [2]int // number of episodes won by each player
+	draws int    // number of draw episodes
+}
+
+// sessionProgress is the record of a running session, safe to read while the session goes on
+type sessionProgress struct {
+	mu       sync.Mutex
+	episodes int // number of episodes played
+	result   sessionResult
+}
+
+// count the episode with the winner's symbol, empty for a draw
+func (sp *sessionProgress) update(ps *playerPair, winner string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.episodes++
+	switch winner {
+	case "":
+		sp.result.draws++
+	case ps[0].symbol:
+		sp.result.wins[0]++
+	default:
+		sp.result.wins[1]++
+	}
+	return
+}
+
+// number of episodes played and their results so far
+func (sp *sessionProgress) get() (int, sessionResult) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.episodes, sp.result
+}
+
+// run a session of episodes between a pair of players. A failure, e.g. a game log that can't
+// be written, ends the session early and is returned with the result of the episodes played;
+// the agents still export what they have learnt.
+func runSession(ps *playerPair, opts sessionOptions) (sessionResult, error) {
+	fmt.Printf("*** Session starts: %v and %v play %v episodes on a %v board *** \n", ps[0].name, ps[1].name, opts.episodes, opts.spec)
+
 	// set up reporting parameters
-	r := false                      // report more frequently
-	v := false                      // robot is verbose
-	if ps[0].being != ps[1].being { // human vs robot
-		r = true // report more frequently
-		for {
-			fmt.Printf("set robot to verbose? (t/f): ")
-			_, err := fmt.Scanf("%t", &v)
-			if err == nil {
-				break
-			}
-		}
+	r := ps.hasHuman() // human is playing, report more frequently
+	for i := range ps {
+		ps[i].agent.startSession(opts.verbose)
 	}
+	t := ps.terminal()
 	for i := range ps {
-		if ps[i].being == "robot" {
-			ps[i].mind.verb = v
+		if pa, ok := ps[i].agent.(planAgent); ok {
+			pa.showPlans(t)
 		}
 	}
 
 	// run episodes
-	for episode := 0; episode < nEpisodes; episode++ {
+	startWins := [2]int{ps[0].wins, ps[1].wins}
+	startDraws := ps[0].draws
+	played := 0
+	err := ps.failure()
+	for episode := 0; episode < opts.episodes && err == nil && !opts.stopped() && !ps.quit(); episode++ {
 		epiNum := episode + 1 // epiNum starts from 1 which is more human readable
-		if math.Mod(float64(epiNum), nPrintEpisode) == 0 && ps[0].being == ps[1].being {
+		if math.Mod(float64(epiNum), nPrintEpisode) == 0 && !r {
 			fmt.Printf("episode #%v \n", epiNum)
 		}
-		runEpisode(ps, r, episode == 0)
+		var winner string
+		var finished bool
+		winner, finished, err = runEpisode(ps, opts, epiNum, r)
+		if !finished { // abandoned
+			break
+		}
+		if opts.ratings != nil && opts.ratings.period == ratePerEpisode {
+			opts.ratings.rate(ps[0].name, ps[1].name, getReward(winner, ps[0].symbol)/2+0.5)
+		}
+		if opts.progress != nil {
+			opts.progress.update(ps, winner)
+		}
+		played++
+	}
+	if err == nil && played < opts.episodes {
+		fmt.Printf("*** Session stopped after %v of %v episodes *** \n", played, opts.episodes)
+	}
+	result := sessionResult{
+		wins:  [2]int{ps[0].wins - startWins[0], ps[1].wins - startWins[1]},
+		draws: ps[0].draws - startDraws,
 	}
 
-	// robot export values
+	// agents export what they have learnt
 	for i := range ps {
-		if ps[i].being == "robot" {
-			exportValues(ps[i].name, ps[i].mind.values)
-			exportValueHistory(ps[i].name, ps[i].mind.demohist)
+		if e := ps[i].agent.export(ps[i]); e != nil && err == nil {
+			err = fmt.Errorf("cannot export %v: %v", ps[i].name, e)
+		}
+	}
+	if opts.ratings != nil {
+		if opts.ratings.period == ratePerSession && played > 0 {
+			opts.ratings.rate(ps[0].name, ps[1].name, (float64(result.wins[0])+0.5*float64(result.draws))/float64(played))
+		}
+		if e := opts.ratings.save(); e != nil && err == nil {
+			err = fmt.Errorf("cannot save ratings: %v", e)
 		}
 	}
-	fmt.Printf("*** Session ends - %v won %v times / %v won %v times *** \n\n", ps[0].name, ps[0].wins, ps[1].name, ps[1].wins)
+	if err != nil {
+		fmt.Printf("*** Session failed after %v of %v episodes: %v *** \n", played, opts.episodes, err)
+	}
+	fmt.Printf("*** Session ends - %v won %v times / %v won %v times / %v draws *** \n\n", ps[0].name, result.wins[0], ps[1].name, result.wins[1], result.draws)
 
-	return
+	return result, err
 }
 
-// run an episode and let players (if robot) remember what they've learnt
-func runEpisode(ps *playerPair, report, firstEpisode bool) {
+// run the episode-th episode of the session and let players remember what they've learnt;
+// return the winner's symbol, empty for a draw, whether the episode is finished, and the
+// failure of an agent or of recording the episode. An episode is abandoned when an agent fails
+// or quits: nobody learns from it, and it's neither recorded nor counted.
+func runEpisode(ps *playerPair, opts sessionOptions, episode int, report bool) (string, bool, error) {
 	var loc location
 	var env environment
+	var moves []location
 	if printSteps { // global const to force reporting
 		report = true
 	}
-	env.initializeEnvironment()
+	env.initializeEnvironment(opts.spec)
 
-	// randomly assign 0 or 1 as the first player ("x")
-	first := rand.Perm(2)[0]
+	// randomly assign 0 or 1 as the first player ("x"), unless it's fixed
+	first := opts.first
+	if first == randomFirst {
+		first = opts.rng.Perm(2)[0]
+	}
 	second := 1 - first
 
 	// first player uses "x"
@@ -117,7 +268,7 @@ func runEpisode(ps *playerPair, report, firstEpisode bool) {
 		fmt.Printf("\n %v(%v) starts first \n", ps[first].name, ps[first].symbol)
 	}
 	s := "o" // current player
-	for !env.gameOver {
+	for !env.gameOver && !ps.abandons() {
 		// switch player and take action
 		if s == "o" {
 			s = "x"
@@ -126,32 +277,51 @@ func runEpisode(ps *playerPair, report, firstEpisode bool) {
 			s = "o"
 			loc = ps[second].playerActs(env)
 		}
+		if ps.abandons() { // the move of an agent that has failed or quit isn't played
+			break
+		}
 
 		// update environment by the action
 		env.updateGameStatus(loc, s)
+		moves = append(moves, loc)
 
-		// update state history and remember the demo states
+		// let players see the board following the move
 		for i := range ps {
-			// The same board is encoded differently by the two players;
-			// each location is viewed not as "x" or "o", but instead as Me or You.
-			state := boardToState(&env.board, ps[i].symbol)
-			ps[i].updateStateSequence(state)
+			ps[i].agent.observe(env, ps[i].symbol)
 		}
 	}
 
-	if firstEpisode {
+	if ps.abandons() {
 		for i := range ps {
-			ps[i].getDemoStates()
+			if fa, ok := ps[i].agent.(forgettingAgent); ok {
+				fa.forget()
+			}
 		}
+		return "", false, ps.failure()
 	}
 
 	if report {
-		env.summarizeEpisode(&ps[first], &ps[second])
+		env.summarizeEpisode(ps[first], ps[second])
+	}
+
+	// record the game
+	var err error
+	if opts.games != nil && opts.games.wants(ps, episode) {
+		gr := gameRecord{
+			Episode: episode, Rows: opts.spec.rows, Cols: opts.spec.cols, K: opts.spec.k,
+			X: newRecordPlayer(ps[first]), O: newRecordPlayer(ps[second]), Moves: moves, Result: env.winner,
+		}
+		if env.winner == "" {
+			gr.Result = "draw"
+		}
+		if e := opts.games.write(&gr); e != nil {
+			err = fmt.Errorf("cannot record the game: %v", e)
+		}
 	}
 
 	// grow some brain
 	ps[first].updatePlayerRecord(env)
 	ps[second].updatePlayerRecord(env)
 
-	return
+	return env.winner, true, err
 }
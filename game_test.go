@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubAgent moves to the first empty location, and counts the episodes it learns from
+type stubAgent struct {
+	learnt int
+}
+
+func (a *stubAgent) kind() string {
+	return "stub"
+}
+
+func (a *stubAgent) startSession(verb bool) {
+	return
+}
+
+func (a *stubAgent) act(env environment, symbol string) location {
+	for irow, row := range env.board {
+		for icol, element := range row {
+			if element == "" {
+				return location{irow, icol}
+			}
+		}
+	}
+	return location{}
+}
+
+func (a *stubAgent) observe(env environment, symbol string) {
+	return
+}
+
+func (a *stubAgent) learn(env environment, symbol string) {
+	a.learnt++
+	return
+}
+
+func (a *stubAgent) export(p *player) error {
+	return nil
+}
+
+// a pair of players with the agents, and options of a session of n episodes between them
+func stubSession(a1, a2 agent, n int) (*playerPair, sessionOptions) {
+	ps := &playerPair{{name: "p1", agent: a1}, {name: "p2", agent: a2}}
+	return ps, sessionOptions{episodes: n, spec: defaultSpec, first: 0, rng: rand.New(rand.NewSource(1))}
+}
+
+// a game log that can't be written ends the session with its failure
+func TestSessionGameLogFailure(t *testing.T) {
+	gl, err := openGameLog(filepath.Join(t.TempDir(), "games.jsonl"), 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl.close()
+	ps, opts := stubSession(&stubAgent{}, &stubAgent{}, 3)
+	opts.games = gl
+	if _, err := runSession(ps, opts); err == nil || !strings.Contains(err.Error(), "cannot record the game") {
+		t.Errorf("got error %v, want the failure of the game log", err)
+	}
+	if n := ps[0].wins + ps[0].draws + ps[0].losses; n != 1 {
+		t.Errorf("%v episodes played, want the session to end after the first", n)
+	}
+}
+
+// failingExport is a stub agent that can't export what it has learnt
+type failingExport struct {
+	stubAgent
+}
+
+func (a *failingExport) export(p *player) error {
+	return errors.New("disk full")
+}
+
+// an agent that can't export ends the session with its failure, after all the episodes
+func TestSessionExportFailure(t *testing.T) {
+	ps, opts := stubSession(&stubAgent{}, &failingExport{}, 3)
+	if _, err := runSession(ps, opts); err == nil || !strings.Contains(err.Error(), "cannot export p2: disk full") {
+		t.Errorf("got error %v, want the failure of the export", err)
+	}
+	if n := ps[0].wins + ps[0].draws + ps[0].losses; n != 3 {
+		t.Errorf("%v episodes played, want 3", n)
+	}
+}
+
+// ratings that can't be saved end the session with their failure, after all the episodes
+func TestSessionRatingsFailure(t *testing.T) {
+	rb, err := loadRatings(filepath.Join(t.TempDir(), "missing", "ratings.json"), ratePerEpisode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, opts := stubSession(&stubAgent{}, &stubAgent{}, 3)
+	opts.ratings = rb
+	if _, err := runSession(ps, opts); err == nil || !strings.Contains(err.Error(), "cannot save ratings") {
+		t.Errorf("got error %v, want the failure of the ratings", err)
+	}
+	if g := rb.get("p1").Games; g != 3 {
+		t.Errorf("p1 has %v rated games, want 3", g)
+	}
+}
+
+// quitter is a stub agent that quits at its second move, like a human leaving the terminal UI
+type quitter struct {
+	stubAgent
+	moves int
+}
+
+func (a *quitter) act(env environment, symbol string) location {
+	a.moves++
+	return a.stubAgent.act(env, symbol)
+}
+
+func (a *quitter) quit() bool {
+	return a.moves >= 2
+}
+
+// the episode a player quits is abandoned: nobody learns from it and it's not counted, and
+// the session stops without a failure
+func TestSessionQuit(t *testing.T) {
+	a := &stubAgent{}
+	ps, opts := stubSession(a, &quitter{}, 3)
+	if _, err := runSession(ps, opts); err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+	if n := ps[0].wins + ps[0].draws + ps[0].losses; n != 0 || a.learnt != 0 {
+		t.Errorf("%v episodes counted and %v learnt, want none", n, a.learnt)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// gameRecord is the record of an episode, written as one line of JSON in a game log
+type gameRecord struct {
+	Episode int          `json:"episode"` // number of the episode in its session, from 1
+	Rows    int          `json:"rows"`
+	Cols    int          `json:"cols"`
+	K       int          `json:"k"`
+	X       recordPlayer `json:"x"`      // the player playing "x", who moves first
+	O       recordPlayer `json:"o"`      // the player playing "o"
+	Moves   []location   `json:"moves"`  // locations of the moves as [row, col], in order
+	Result  string       `json:"result"` // "x" or "o" for the winner, or "draw"
+}
+
+type recordPlayer struct {
+	Name  string      `json:"name"`
+	Kind  string      `json:"kind"`
+	Specs *modelSpecs `json:"specs,omitempty"` // specs of a robot at the time of the episode
+}
+
+// specAgent is an agent with specs to be recorded
+type specAgent interface {
+	modelSpecs() modelSpecs
+}
+
+func newRecordPlayer(p *player) recordPlayer {
+	rp := recordPlayer{Name: p.name, Kind: p.agent.kind()}
+	if sa, ok := p.agent.(specAgent); ok {
+		specs := sa.modelSpecs()
+		rp.Specs = &specs
+	}
+	return rp
+}
+
+// gameLog appends game records to a JSON Lines file; it's shared by the sessions running in
+// parallel
+type gameLog struct {
+	mu        sync.Mutex
+	file      *os.File
+	every     int  // log every N-th episode of a session
+	humanOnly bool // log only the episodes with a human player
+}
+
+// open the game log, appending to the file if it exists
+func openGameLog(filename string, every int, humanOnly bool) (*gameLog, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &gameLog{file: file, every: every, humanOnly: humanOnly}, nil
+}
+
+// check whether the episode passes the filters of the log
+func (gl *gameLog) wants(ps *playerPair, episode int) bool {
+	if gl.humanOnly && !ps.hasHuman() {
+		return false
+	}
+	return episode%gl.every == 0
+}
+
+// write the record as a line of the log
+func (gl *gameLog) write(gr *gameRecord) error {
+	d, err := json.Marshal(gr)
+	if err != nil {
+		return err
+	}
+	gl.mu.Lock()
+	defer gl.mu.Unlock()
+	_, err = gl.file.Write(append(d, '\n'))
+	return err
+}
+
+func (gl *gameLog) close() error {
+	return gl.file.Close()
+}
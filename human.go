@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+)
+
+// human enters moves at the terminal and learns nothing the program can see
+type human struct {
+	wins     int       // number of episodes won in the session, shown by the terminal UI
+	losses   int       // number of episodes lost in the session
+	draws    int       // number of draw episodes in the session
+	cursor   location  // latest location chosen with the cursor of the terminal UI
+	tui      *terminal // terminal UI of the human; the line prompts if nil
+	quitting bool      // the human has quit the session, which abandons the episode and stops
+}
+
+func (h *human) kind() string {
+	return "human"
+}
+
+func (h *human) startSession(verb bool) {
+	h.wins, h.losses, h.draws = 0, 0, 0
+	h.quitting = false
+	if terminalUI && h.tui == nil {
+		h.tui = &terminal{}
+	}
+	return
+}
+
+// the human has quit the session
+func (h *human) quit() bool {
+	return h.quitting
+}
+
+// let the human choose the move; once the human has quit, no move is chosen, as the episode
+// is abandoned
+func (h *human) act(env environment, symbol string) (actionLocation location) {
+	if h.quitting {
+		return location{}
+	}
+	if h.tui != nil {
+		l, err := h.tui.chooseMove(env, symbol, h)
+		if err == nil {
+			fmt.Printf("you are making a move to %v \n", l)
+			return l
+		}
+		if err == errQuit {
+			fmt.Print("you quit the session \n")
+			h.quitting = true
+			return location{}
+		}
+		fmt.Printf("cannot use the terminal UI, falling back to the prompts: %v \n", err)
+		h.tui = nil
+	}
+	printBoard(&env.board, true)
+	for {
+		var row, col int
+		fmt.Printf("Enter location (row column, from 0 0 to %v %v): ", env.spec.rows-1, env.spec.cols-1)
+		_, err := fmt.Scanf("%d%d", &row, &col)
+		if err == nil {
+			l := location{row, col}
+			if row < 0 || row >= env.spec.rows || col < 0 || col >= env.spec.cols {
+				fmt.Printf("%v is off the board \n", l)
+				continue
+			}
+			if env.board[l[0]][l[1]] == "" {
+				fmt.Printf("you are making a move to %v \n", l)
+				return l
+			}
+		}
+		// invalid move, re-enter location
+		fmt.Print("invalid move \n")
+	}
+}
+
+func (h *human) observe(env environment, symbol string) {
+	return
+}
+
+// count the result of the episode; the plan board of the episode is no longer of use
+func (h *human) learn(env environment, symbol string) {
+	switch env.winner {
+	case "":
+		h.draws++
+	case symbol:
+		h.wins++
+	default:
+		h.losses++
+	}
+	if h.tui != nil {
+		h.tui.plan = nil
+	}
+	return
+}
+
+func (h *human) export(p *player) error {
+	return nil
+}
@@ -1,12 +1,19 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"math/rand"
+	"os"
+	"runtime"
+	"sync"
 	"time"
 )
 
 // Global constants
-const boardSize = 3         // length/width of the board
+const defaultRows = 3       // default number of rows of the board
+const defaultCols = 3       // default number of columns of the board
+const defaultWinLength = 3  // default number of symbols in a row to win
 const nDemoStates = 3       // number of states for history demonstration
 const printSteps = false    // print board and plan at each step
 const alpha = 0.5           // default alpha (learning rate)
@@ -19,16 +26,163 @@ const drawReward = 0.0      // reward for draw game
 const loseReward = -1.0     // reward for losing the game
 const nPrintHistory = 500   // print value history every N points
 const nPrintEpisode = 10000 // print episode number every N episodes
+const nWorstPositions = 10  // number of worst positions listed in the optimality report
 
 // main
 func main() {
-	// set random seed to time
-	rand.Seed(time.Now().UTC().UnixNano())
+	configFile := flag.String("config", "", "run in batch mode with the players and sessions declared in this JSON config file")
+	reportFile := flag.String("report", "", "report how often the greedy moves of the robot in this model file are optimal")
+	ratingsFile := flag.String("ratings", "ratings.json", "file the Elo and Glicko-2 ratings of the players are kept in; no ratings if empty")
+	ratingPeriod := flag.String("rating-period", ratePerSession, "rate players per \""+ratePerEpisode+"\" or per \""+ratePerSession+"\"")
+	logFile := flag.String("log", "", "append a JSON record of each episode to this game log")
+	logEvery := flag.Int("log-every", 1, "record only every N-th episode of each session in the game log")
+	logHumans := flag.Bool("log-humans", false, "record only the episodes with a human player in the game log")
+	replayFile := flag.String("replay", "", "step through the games of this game log")
+	robotFile := flag.String("robot", "", "model file of a robot whose plan boards are shown in the replay")
+	tuiFlag := flag.Bool("tui", false, "let human players choose their moves with the arrow keys in a full-screen terminal UI")
+	engineFile := flag.String("engine", "", "speak the engine protocol on stdin and stdout with the robot in this model file")
+	serveAddr := flag.String("serve", "", "serve games against the players in a browser on this address, e.g. :8080")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of sessions of the config run in parallel")
+	seed := flag.Int64("seed", 0, "seed of the random sources, to reproduce a run; drawn from the clock if zero")
+	solveOpponent := flag.String("solve", "", "solve the exact state values against this opponent model, \""+optimalOpponent+"\", \""+randomOpponent+"\" or \""+epsOpponent+":E\"")
+	solveGam := flag.Float64("gam", gamma, "discount factor of the solver")
+	solveSym := flag.Bool("sym", false, "solve on canonical states")
+	boardFlag := flag.String("board", fmt.Sprintf("%v,%v,%v", defaultRows, defaultCols, defaultWinLength), "board of the report or the solver, as rows,cols,k")
+	flag.Parse()
 
-	// create players
-	players := createPlayers()
+	terminalUI = *tuiFlag
 
-	// create sessions
-	createSessions(players)
+	// the engine protocol owns stdout, so everything else is printed on stderr
+	protocol := os.Stdout
+	if *engineFile != "" {
+		os.Stdout = os.Stderr
+	}
 
+	// seed the random sources
+	if *seed == 0 {
+		*seed = time.Now().UTC().UnixNano()
+	}
+	seeder = rand.New(rand.NewSource(*seed))
+	fmt.Printf("random seed: %v \n", *seed)
+
+	// optimality report
+	if *reportFile != "" {
+		var p player
+		spec, err := parseBoardSpec(*boardFlag)
+		if err == nil {
+			err = p.loadModel("", *reportFile)
+		}
+		if err == nil {
+			err = reportOptimality(&p, spec)
+		}
+		exitOnError(err)
+		return
+	}
+
+	// replay viewer
+	if *replayFile != "" {
+		exitOnError(replay(*replayFile, *robotFile))
+		return
+	}
+
+	// engine mode
+	if *engineFile != "" {
+		var p player
+		err := p.loadModel("", *engineFile)
+		if err == nil {
+			err = runEngine(&p, os.Stdin, protocol)
+		}
+		exitOnError(err)
+		return
+	}
+
+	// exact state values
+	if *solveOpponent != "" {
+		spec, err := parseBoardSpec(*boardFlag)
+		if err == nil {
+			err = solveValues(spec, *solveOpponent, *solveGam, *solveSym)
+		}
+		exitOnError(err)
+		return
+	}
+
+	// ratings
+	var ratings *ratingBook
+	if *ratingsFile != "" {
+		var err error
+		ratings, err = loadRatings(*ratingsFile, *ratingPeriod)
+		exitOnError(err)
+	}
+
+	// game log
+	var games *gameLog
+	if *logFile != "" {
+		if *logEvery < 1 {
+			exitOnError(fmt.Errorf("invalid number of episodes %v between records", *logEvery))
+		}
+		var err error
+		games, err = openGameLog(*logFile, *logEvery, *logHumans)
+		exitOnError(err)
+		defer games.close()
+	}
+
+	// web server
+	if *serveAddr != "" {
+		var players []player
+		if *configFile != "" {
+			cfg, err := loadConfig(*configFile)
+			if err == nil {
+				players, err = cfg.createPlayers()
+			}
+			exitOnError(err)
+		} else {
+			players = createPlayers()
+		}
+		exitOnError(serve(*serveAddr, players, ratings, games))
+		return
+	}
+
+	// batch mode
+	if *workers < 1 {
+		exitOnError(fmt.Errorf("invalid number of workers %v", *workers))
+	}
+	if *configFile != "" {
+		cfg, err := loadConfig(*configFile)
+		if err == nil {
+			err = runBatch(cfg, ratings, games, *workers)
+		}
+		exitOnError(err)
+	} else {
+		// create players
+		players := createPlayers()
+
+		// create sessions
+		createSessions(players, ratings, games)
+	}
+
+	if ratings != nil {
+		ratings.printLeaderboard()
+	}
+
+}
+
+// print the error and exit with non-zero status, if there's an error
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return
+}
+
+// seeder draws the seeds of the random sources of players and sessions, so a run is
+// reproduced by its seed
+var seeder *rand.Rand
+var seederMu sync.Mutex // the server creates players and sessions from its handlers
+
+// create a random source of its own for a player or a session
+func newRand() *rand.Rand {
+	seederMu.Lock()
+	defer seederMu.Unlock()
+	return rand.New(rand.NewSource(seeder.Int63()))
 }
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// seed the random sources as main does, with a fixed seed so the tests are reproducible
+func TestMain(m *testing.M) {
+	seeder = rand.New(rand.NewSource(1))
+	os.Exit(m.Run())
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// rollouts of the Monte Carlo tree search
+const (
+	randomRollout = "random" // uniformly random moves to the end of the episode
+	valueRollout  = "value"  // moves chosen by the policy of the prior robot
+)
+
+const defaultPlayouts = 1000   // playouts per move of an MCTS player without budget
+const uctConstant = math.Sqrt2 // exploration constant of the UCT selection
+const priorVisits = 10         // virtual visits with which the prior robot's value seeds a new node
+
+// mctsNode is a position in the search tree, reached by a move
+type mctsNode struct {
+	move     location    // move leading to the node
+	mover    string      // symbol of the player who made the move
+	over     bool        // the episode is over after the move
+	winner   string      // winner after the move, if over
+	visits   float64     // number of playouts through the node, including virtual visits
+	total    float64     // sum of the rewards of the playouts, in the perspective of the mover
+	parent   *mctsNode   // nil at the root
+	children []*mctsNode // expanded children
+	untried  []location  // moves not expanded yet
+}
+
+// mcts searches the game tree by Monte Carlo tree search with UCT selection. It learns
+// nothing between moves; a trained robot, if given, values the new nodes as a prior and
+// can guide the rollouts.
+type mcts struct {
+	name     string        // name of the player, for printing
+	playouts int           // playouts per move; unlimited if zero and there's a time budget
+	budget   time.Duration // time per move; unlimited if zero
+	rollout  string        // randomRollout or valueRollout
+	prior    *robot        // robot whose state values seed new nodes; none if nil
+	verb     bool          // verbose
+	rng      *rand.Rand    // random source of the rollouts and tie-breaks
+	plans    *terminal     // terminal UI the verbose player shows its plan boards in; none if nil
+}
+
+func newMCTS(name string, playouts int, budget time.Duration, rollout string, prior *robot) *mcts {
+	if playouts == 0 && budget == 0 {
+		playouts = defaultPlayouts
+	}
+	if rollout == "" {
+		rollout = randomRollout
+	}
+	return &mcts{name: name, playouts: playouts, budget: budget, rollout: rollout, prior: prior, rng: newRand()}
+}
+
+// load the robot from the model file as the prior of an MCTS player
+func loadPrior(filename string) (*robot, error) {
+	var p player
+	if err := p.loadModel("", filename); err != nil {
+		return nil, err
+	}
+	r, ok := p.agent.(*robot)
+	if !ok {
+		return nil, fmt.Errorf("model %v is not of a robot learning state values", filename)
+	}
+	return r, nil
+}
+
+func (m *mcts) kind() string {
+	return "mcts"
+}
+
+func (m *mcts) startSession(verb bool) {
+	m.verb = verb
+	return
+}
+
+func (m *mcts) showPlans(t *terminal) {
+	m.plans = t
+	return
+}
+
+// search from the board and move to the most visited child of the root; at least one playout
+// is run, whatever the budget, so the root has a child to move to
+func (m *mcts) act(env environment, symbol string) location {
+	root := m.newNode(nil, env.board, env.spec.k, location{}, opponentSymbol(symbol))
+	start := time.Now()
+	n := 0
+	for n == 0 || (m.playouts == 0 || n < m.playouts) && (m.budget == 0 || time.Since(start) < m.budget) {
+		m.playout(root, env.board, env.spec.k)
+		n++
+	}
+
+	plan := make(board, len(env.board)) // only useful for printing out the plan
+	for irow, row := range env.board {
+		plan[irow] = append([]string{}, row...)
+	}
+	var best []*mctsNode
+	for _, c := range root.children {
+		plan[c.move[0]][c.move[1]] = strconv.FormatFloat(c.total/c.visits, 'f', 2, 64)
+		if len(best) == 0 || c.visits > best[0].visits {
+			best = []*mctsNode{c}
+		} else if c.visits == best[0].visits {
+			best = append(best, c)
+		}
+	}
+	choice := best[m.rng.Intn(len(best))] // ties are broken randomly
+	if m.verb || printSteps {
+		fmt.Printf("player %v(%v)'s plan board (mean rewards of %v playouts): \n", m.name, symbol, n)
+		printBoard(&plan, true)
+		fmt.Printf("player %v(%v) takes action at %v, visited %v times \n", m.name, symbol, choice.move, choice.visits)
+		m.plans.showPlan(fmt.Sprintf("%v(%v)'s plan board (mean rewards)", m.name, symbol), plan)
+	}
+	return choice.move
+}
+
+func (m *mcts) observe(env environment, symbol string) {
+	return
+}
+
+func (m *mcts) learn(env environment, symbol string) {
+	return
+}
+
+func (m *mcts) export(p *player) error {
+	return nil
+}
+
+// create a node for the board after the move; the prior robot, if any, gives it virtual visits
+func (m *mcts) newNode(parent *mctsNode, b board, k int, move location, mover string) *mctsNode {
+	nd := &mctsNode{move: move, mover: mover, parent: parent}
+	if parent != nil {
+		nd.over = isWinningMove(b, move, k)
+		if nd.over {
+			nd.winner = mover
+		} else {
+			nd.over = getEmpties(b) == 0
+		}
+	}
+	if !nd.over {
+		for irow, row := range b {
+			for icol, element := range row {
+				if element == "" {
+					nd.untried = append(nd.untried, location{irow, icol})
+				}
+			}
+		}
+	}
+	if m.prior != nil && parent != nil {
+		var value float64
+		if nd.over {
+			value = getReward(nd.winner, mover)
+		} else {
+			value = m.prior.stateValue(m.prior.encodeState(&b, mover))
+		}
+		nd.visits, nd.total = priorVisits, priorVisits*value
+	}
+	return nd
+}
+
+// run a playout from the root: select down the tree by UCT, expand a node, roll out to the
+// end of the episode and back up the reward
+func (m *mcts) playout(root *mctsNode, b board, k int) {
+	// work on a copy, so the caller's board is never touched
+	c := make(board, len(b))
+	for irow := range b {
+		c[irow] = append([]string{}, b[irow]...)
+	}
+
+	// selection
+	nd := root
+	for len(nd.untried) == 0 && len(nd.children) > 0 {
+		nd = nd.selectChild()
+		c[nd.move[0]][nd.move[1]] = nd.mover
+	}
+
+	// expansion
+	if len(nd.untried) > 0 {
+		i := m.rng.Intn(len(nd.untried))
+		move := nd.untried[i]
+		nd.untried[i] = nd.untried[len(nd.untried)-1]
+		nd.untried = nd.untried[:len(nd.untried)-1]
+		mover := opponentSymbol(nd.mover)
+		c[move[0]][move[1]] = mover
+		child := m.newNode(nd, c, k, move, mover)
+		nd.children = append(nd.children, child)
+		nd = child
+	}
+
+	// simulation
+	winner := nd.winner
+	if !nd.over {
+		winner = m.simulate(c, k, opponentSymbol(nd.mover))
+	}
+
+	// backpropagation
+	for ; nd != nil; nd = nd.parent {
+		nd.visits++
+		nd.total += getReward(winner, nd.mover)
+	}
+	return
+}
+
+// child of the best upper confidence bound, in the perspective of the player to move
+func (nd *mctsNode) selectChild() *mctsNode {
+	var best *mctsNode
+	bestScore := math.Inf(-1)
+	for _, c := range nd.children {
+		score := c.total/c.visits + uctConstant*math.Sqrt(math.Log(nd.visits)/c.visits)
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// play the board to the end, starting with the player playing symbol; return the winner's
+// symbol, empty for a draw
+func (m *mcts) simulate(b board, k int, symbol string) string {
+	env := environment{spec: boardSpec{rows: len(b), cols: len(b[0]), k: k}, board: b}
+	for s := symbol; ; s = opponentSymbol(s) {
+		var moves []location
+		var probs []float64
+		if m.rollout == valueRollout {
+			var gains []float64
+			var states []stateKey
+			moves, states, gains, _ = m.prior.planMoves(env, s)
+			counts := make([]uint, len(states))
+			for i, state := range states {
+				counts[i] = m.prior.mind.counts[state]
+			}
+			probs = m.prior.mind.specs.explore.probabilities(gains, counts, m.prior.eps())
+		} else {
+			for irow, row := range b {
+				for icol, element := range row {
+					if element == "" {
+						moves = append(moves, location{irow, icol})
+					}
+				}
+			}
+		}
+		var move location
+		if probs != nil {
+			move = moves[sample(probs, m.rng)]
+		} else {
+			move = moves[m.rng.Intn(len(moves))]
+		}
+		b[move[0]][move[1]] = s
+		if isWinningMove(b, move, k) {
+			return s
+		}
+		if len(moves) == 1 {
+			return "" // draw
+		}
+	}
+}
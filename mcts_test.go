@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// a time budget too small for any playout still gives a move
+func TestMCTSTinyBudget(t *testing.T) {
+	m := newMCTS("m", 0, time.Nanosecond, randomRollout, nil)
+	var env environment
+	env.initializeEnvironment(defaultSpec)
+	env.board[1][1] = "x"
+	loc := m.act(env, "o")
+	if env.board[loc[0]][loc[1]] != "" {
+		t.Errorf("move %v is not an empty location", loc)
+	}
+}
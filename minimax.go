@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// bound of a score stored in the transposition table
+const (
+	exactScore = iota
+	lowerBound
+	upperBound
+)
+
+type ttEntry struct {
+	score float64 // score for the player to move
+	depth int     // number of plies searched below the state
+	bound int     // exactScore, lowerBound or upperBound
+}
+
+// transposition table; each state, in the perspective of the player to move, maps to its score
+type transpositionTable map[stateKey]ttEntry
+
+// minimax searches the game tree with alpha-beta pruning. With unlimited depth it plays
+// perfectly; with limited depth, positions at the horizon are scored by a heuristic.
+//
+// Scores are in the perspective of the player to move: a win scores 1 plus the number of
+// empty locations left on the board (so faster wins score higher), a loss the negative of
+// it, a draw 0, and the heuristic is strictly between -1 and 1. A score only depends on the
+// position, so it can be shared through the transposition table.
+type minimax struct {
+	name   string                           // name of the player, for printing
+	depth  int                              // maximum number of plies to search; unlimited if zero
+	tables map[boardSpec]transpositionTable // one table per board, as states don't encode k
+	verb   bool                             // verbose
+	rng    *rand.Rand                       // random source of tie-breaks
+	plans  *terminal                        // terminal UI the verbose player shows its plan boards in; none if nil
+}
+
+func newMinimax(name string, depth int) *minimax {
+	return &minimax{name: name, depth: depth, tables: map[boardSpec]transpositionTable{}, rng: newRand()}
+}
+
+func (m *minimax) kind() string {
+	return "minimax"
+}
+
+func (m *minimax) startSession(verb bool) {
+	m.verb = verb
+	return
+}
+
+func (m *minimax) showPlans(t *terminal) {
+	m.plans = t
+	return
+}
+
+// choose the move with the best score; ties are broken randomly
+func (m *minimax) act(env environment, symbol string) location {
+	scores := m.moveScores(env.board, env.spec, symbol)
+	plan := make(board, len(env.board)) // only useful for printing out the plan
+	bestScore := math.Inf(-1)
+	var best []location
+	for irow, row := range env.board {
+		plan[irow] = make([]string, len(row))
+		for icol, element := range row {
+			plan[irow][icol] = element
+			score, ok := scores[location{irow, icol}]
+			if !ok {
+				continue
+			}
+			plan[irow][icol] = strconv.FormatFloat(score, 'f', 2, 64)
+			if score > bestScore {
+				bestScore = score
+				best = []location{{irow, icol}}
+			} else if score == bestScore {
+				best = append(best, location{irow, icol})
+			}
+		}
+	}
+	actionLocation := best[m.rng.Intn(len(best))]
+	if m.verb || printSteps {
+		fmt.Printf("player %v(%v)'s plan board: \n", m.name, symbol)
+		printBoard(&plan, true)
+		fmt.Printf("player %v(%v) takes action at %v \n", m.name, symbol, actionLocation)
+		m.plans.showPlan(fmt.Sprintf("%v(%v)'s plan board", m.name, symbol), plan)
+	}
+	return actionLocation
+}
+
+func (m *minimax) observe(env environment, symbol string) {
+	return
+}
+
+func (m *minimax) learn(env environment, symbol string) {
+	return
+}
+
+func (m *minimax) export(p *player) error {
+	return nil
+}
+
+// score every possible move of the player to move on the board
+func (m *minimax) moveScores(b board, spec boardSpec, symbol string) map[location]float64 {
+	tt, ok := m.tables[spec]
+	if !ok {
+		tt = transpositionTable{}
+		m.tables[spec] = tt
+	}
+	empties := getEmpties(b)
+	depth := m.depth
+	if depth == 0 || depth > empties {
+		depth = empties
+	}
+	// work on a copy, so the caller's board is never touched
+	c := make(board, len(b))
+	for irow := range b {
+		c[irow] = append([]string{}, b[irow]...)
+	}
+	scores := map[location]float64{}
+	for _, loc := range orderedMoves(c) {
+		scores[loc] = m.scoreMove(c, spec.k, symbol, loc, empties, depth, math.Inf(-1), math.Inf(1), tt)
+	}
+	return scores
+}
+
+// score of the move to loc for the player playing symbol, who has depth plies to search
+func (m *minimax) scoreMove(b board, k int, symbol string, loc location, empties, depth int, alpha, beta float64, tt transpositionTable) float64 {
+	b[loc[0]][loc[1]] = symbol
+	defer func() { b[loc[0]][loc[1]] = "" }()
+	if isWinningMove(b, loc, k) {
+		return float64(empties)
+	}
+	if empties == 1 {
+		return 0.0 // draw
+	}
+	return -m.search(b, k, opponentSymbol(symbol), empties-1, depth-1, -beta, -alpha, tt)
+}
+
+// negamax search with alpha-beta pruning, scoring the board for the player to move
+func (m *minimax) search(b board, k int, symbol string, empties, depth int, alpha, beta float64, tt transpositionTable) float64 {
+	if depth == 0 {
+		return heuristic(b, k, symbol)
+	}
+	state := boardToState(&b, symbol)
+	if e, ok := tt[state]; ok && e.depth >= depth {
+		switch e.bound {
+		case exactScore:
+			return e.score
+		case lowerBound:
+			alpha = math.Max(alpha, e.score)
+		case upperBound:
+			beta = math.Min(beta, e.score)
+		}
+		if alpha >= beta {
+			return e.score
+		}
+	}
+	alphaOrig := alpha
+	best := math.Inf(-1)
+	for _, loc := range orderedMoves(b) {
+		score := m.scoreMove(b, k, symbol, loc, empties, depth, alpha, beta, tt)
+		best = math.Max(best, score)
+		alpha = math.Max(alpha, score)
+		if alpha >= beta {
+			break
+		}
+	}
+	e := ttEntry{score: best, depth: depth, bound: exactScore}
+	if best <= alphaOrig {
+		e.bound = upperBound
+	} else if best >= beta {
+		e.bound = lowerBound
+	}
+	tt[state] = e
+	return best
+}
+
+// empty locations, those closer to the center first, which tend to be better moves
+func orderedMoves(b board) []location {
+	var moves []location
+	var dists []float64
+	for irow, row := range b {
+		for icol, element := range row {
+			if element != "" {
+				continue
+			}
+			d := math.Abs(float64(irow)-float64(len(b)-1)/2) + math.Abs(float64(icol)-float64(len(row)-1)/2)
+			// insertion sort by distance to the center
+			i := len(moves)
+			moves = append(moves, location{})
+			dists = append(dists, 0)
+			for i > 0 && dists[i-1] > d {
+				moves[i], dists[i] = moves[i-1], dists[i-1]
+				i--
+			}
+			moves[i], dists[i] = location{irow, icol}, d
+		}
+	}
+	return moves
+}
+
+// estimate the board for the player to move, strictly between -1 and 1. Every k locations
+// in a row that are still open to only one of the players count for that player, more so
+// the more of them the player already occupies.
+func heuristic(b board, k int, symbol string) float64 {
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	var h float64
+	for irow, row := range b {
+		for icol := range row {
+			for _, d := range directions {
+				erow, ecol := irow+(k-1)*d[0], icol+(k-1)*d[1]
+				if erow < 0 || erow >= len(b) || ecol < 0 || ecol >= len(row) {
+					continue
+				}
+				var mine, theirs int
+				for i := 0; i < k; i++ {
+					element := b[irow+i*d[0]][icol+i*d[1]]
+					if element == symbol {
+						mine++
+					} else if element != "" {
+						theirs++
+					}
+				}
+				if mine > 0 && theirs == 0 {
+					h += math.Pow(4, float64(mine-1))
+				} else if theirs > 0 && mine == 0 {
+					h -= math.Pow(4, float64(theirs-1))
+				}
+			}
+		}
+	}
+	return 0.99 * h / (1 + math.Abs(h))
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// version of the model file format, increased whenever the format changes
+// 1: Monte-Carlo robots
+// 2: adds the learning algorithm and the action values of Q-learning robots
+// 3: adds draws and losses to the record
+// 4: adds the schedules of alp and eps and the number of episodes learnt from
+// 5: adds the exploration policy
+// 6: adds the rewards and shaping terms
+const modelVersion = 6
+
+// modelFile is the lossless on-disk form of a robot's mind and record
+type modelFile struct {
+	Version  int                  `json:"version"`
+	Name     string               `json:"name"`
+	Specs    modelSpecs           `json:"specs"`
+	Wins     int                  `json:"wins"`
+	Draws    int                  `json:"draws"`
+	Losses   int                  `json:"losses"`
+	Episodes int                  `json:"episodes"`           // number of episodes learnt from, which the schedules run on
+	Values   map[string]float64   `json:"values,omitempty"`   // textual state key to value
+	Counts   map[string]uint      `json:"counts,omitempty"`   // textual state key to count
+	Demohist map[string][]float64 `json:"demohist,omitempty"` // textual state key to value history
+	Qvalues  []modelAction        `json:"qvalues,omitempty"`  // action values of a Q-learning robot
+}
+
+type modelSpecs struct {
+	Algo        string        `json:"algo"`
+	Alp         float64       `json:"alp"` // current alp; the constant alp of a model without schedule
+	Eps         float64       `json:"eps"` // current eps; the constant eps of a model without schedule
+	Gam         float64       `json:"gam"`
+	Lam         float64       `json:"lam"`
+	Sym         bool          `json:"sym"`
+	AlpSchedule string        `json:"alp_schedule,omitempty"` // text form of the schedule of alp
+	EpsSchedule string        `json:"eps_schedule,omitempty"` // text form of the schedule of eps
+	Explore     string        `json:"explore,omitempty"`      // text form of the exploration policy
+	Rewards     *modelRewards `json:"rewards,omitempty"`      // the default rewards if omitted
+}
+
+type modelRewards struct {
+	Win    float64 `json:"win"`
+	Draw   float64 `json:"draw"`
+	Lose   float64 `json:"lose"`
+	Threat float64 `json:"threat"`
+	Block  float64 `json:"block"`
+	Move   float64 `json:"move"`
+}
+
+type modelAction struct {
+	State string  `json:"state"` // textual state key
+	Row   int     `json:"row"`
+	Col   int     `json:"col"`
+	Value float64 `json:"value"`
+	Count uint    `json:"count"`
+}
+
+// specs of a robot that has learnt from the given number of episodes
+func newModelSpecs(rs robotSpecs, episodes int) modelSpecs {
+	return modelSpecs{
+		Algo: rs.algo, Alp: rs.alp.at(episodes), Eps: rs.eps.at(episodes), Gam: rs.gam, Lam: rs.lam, Sym: rs.sym,
+		AlpSchedule: rs.alp.String(), EpsSchedule: rs.eps.String(), Explore: rs.explore.String(),
+		Rewards: &modelRewards{
+			Win: rs.rewards.win, Draw: rs.rewards.draw, Lose: rs.rewards.lose,
+			Threat: rs.rewards.threat, Block: rs.rewards.block, Move: rs.rewards.move,
+		},
+	}
+}
+
+func (ms modelSpecs) robotSpecs() (robotSpecs, error) {
+	rs := robotSpecs{algo: ms.Algo, alp: constantSchedule(ms.Alp), eps: constantSchedule(ms.Eps), gam: ms.Gam, lam: ms.Lam, sym: ms.Sym, rewards: defaultRewards}
+	if mr := ms.Rewards; mr != nil {
+		rs.rewards = rewardSpecs{win: mr.Win, draw: mr.Draw, lose: mr.Lose, threat: mr.Threat, block: mr.Block, move: mr.Move}
+	}
+	var err error
+	if ms.AlpSchedule != "" {
+		if rs.alp, err = parseSchedule(ms.AlpSchedule); err != nil {
+			return rs, err
+		}
+	}
+	if ms.EpsSchedule != "" {
+		if rs.eps, err = parseSchedule(ms.EpsSchedule); err != nil {
+			return rs, err
+		}
+	}
+	if rs.explore, err = parseExploration(ms.Explore); err != nil {
+		return rs, err
+	}
+	return rs, nil
+}
+
+// file name of the model of a robot
+func modelFilename(name string) string {
+	return name + ".model.json"
+}
+
+// write the model to the model file of the robot; the file is replaced at once, so it can be
+// read, e.g. downloaded from the server, while the robot saves it
+func writeModel(m modelFile) error {
+	d, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	filename := modelFilename(m.Name)
+	if err := ioutil.WriteFile(filename+".tmp", d, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+	fmt.Printf("%v's model saved into %v \n", m.Name, filename)
+	return nil
+}
+
+// specs of the robot as recorded in its model
+func (r *robot) modelSpecs() modelSpecs {
+	return newModelSpecs(r.mind.specs, r.mind.episodes)
+}
+
+// write the robot's specs, state values, state counts and record to its model file
+func (r *robot) saveModel(p *player) error {
+	m := modelFile{
+		Version:  modelVersion,
+		Name:     p.name,
+		Specs:    r.modelSpecs(),
+		Wins:     p.wins,
+		Draws:    p.draws,
+		Losses:   p.losses,
+		Episodes: r.mind.episodes,
+		Values:   make(map[string]float64, len(r.mind.values)),
+		Counts:   make(map[string]uint, len(r.mind.counts)),
+		Demohist: make(map[string][]float64, len(r.mind.demohist)),
+	}
+	for state, value := range r.mind.values {
+		m.Values[state.String()] = value
+	}
+	for state, count := range r.mind.counts {
+		m.Counts[state.String()] = count
+	}
+	for state, hist := range r.mind.demohist {
+		m.Demohist[state.String()] = hist
+	}
+	return writeModel(m)
+}
+
+// specs of the Q-learning robot as recorded in its model
+func (q *qRobot) modelSpecs() modelSpecs {
+	return newModelSpecs(q.specs, q.episodes)
+}
+
+// write the Q-learning robot's specs, action values, action counts and record to its model file
+func (q *qRobot) saveModel(p *player) error {
+	m := modelFile{
+		Version:  modelVersion,
+		Name:     p.name,
+		Specs:    q.modelSpecs(),
+		Wins:     p.wins,
+		Draws:    p.draws,
+		Losses:   p.losses,
+		Episodes: q.episodes,
+		Qvalues:  make([]modelAction, 0, len(q.qvalues)),
+	}
+	for _, a := range sortedActions(q.qvalues) {
+		m.Qvalues = append(m.Qvalues, modelAction{
+			State: a.state.String(), Row: a.loc[0], Col: a.loc[1], Value: q.qvalues[a], Count: q.counts[a]})
+	}
+	return writeModel(m)
+}
+
+// restore a robot from a model file; the robot takes the given name, or the name in the
+// model if none is given
+func (p *player) loadModel(name, filename string) error {
+	d, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var m modelFile
+	if err := json.Unmarshal(d, &m); err != nil {
+		return fmt.Errorf("cannot read model %v: %v", filename, err)
+	}
+	if m.Version < 1 || m.Version > modelVersion {
+		return fmt.Errorf("model %v has version %v, expected up to %v", filename, m.Version, modelVersion)
+	}
+	if name == "" {
+		name = m.Name
+	}
+	var a agent
+	var size int
+	if m.Specs.Algo == qLearning {
+		a, err = m.qRobot(name)
+		size = len(m.Qvalues)
+	} else {
+		a, err = m.robot(name)
+		size = len(m.Values)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read model %v: %v", filename, err)
+	}
+	*p = player{name: name, wins: m.Wins, draws: m.Draws, losses: m.Losses, agent: a}
+	fmt.Printf("%v is loaded from %v with %v values \n", name, filename, size)
+	return nil
+}
+
+// rebuild a Monte-Carlo robot from the model
+func (m *modelFile) robot(name string) (*robot, error) {
+	rs, err := m.Specs.robotSpecs()
+	if err != nil {
+		return nil, err
+	}
+	r := newRobot(name, rs)
+	r.mind.episodes = m.Episodes
+	for s, value := range m.Values {
+		state, err := parseStateKey(s)
+		if err != nil {
+			return nil, err
+		}
+		r.mind.values[state] = value
+	}
+	for s, count := range m.Counts {
+		state, err := parseStateKey(s)
+		if err != nil {
+			return nil, err
+		}
+		r.mind.counts[state] = count
+	}
+	for s, hist := range m.Demohist {
+		state, err := parseStateKey(s)
+		if err != nil {
+			return nil, err
+		}
+		r.mind.demohist[state] = hist
+	}
+	return r, nil
+}
+
+// rebuild a Q-learning robot from the model
+func (m *modelFile) qRobot(name string) (*qRobot, error) {
+	rs, err := m.Specs.robotSpecs()
+	if err != nil {
+		return nil, err
+	}
+	q := newQRobot(name, rs)
+	q.episodes = m.Episodes
+	for _, ma := range m.Qvalues {
+		state, err := parseStateKey(ma.State)
+		if err != nil {
+			return nil, err
+		}
+		a := action{state, location{ma.Row, ma.Col}}
+		q.qvalues[a] = ma.Value
+		q.counts[a] = ma.Count
+	}
+	return q, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// job is a session or a tournament of a batch run. While it runs, it owns its players: no
+// other job touches them, so a robot is never trained by two jobs at once.
+type job struct {
+	players []*player                      // players owned by the job
+	run     func() (*sessionResult, error) // run the job; a session returns its result and its failure
+}
+
+// check whether the job needs the terminal, which only one job can use at a time
+func (jb job) needsTerminal() bool {
+	for _, p := range jb.players {
+		if p.agent.kind() == "human" {
+			return true
+		}
+	}
+	return false
+}
+
+// check whether two jobs share a player or both need the terminal
+func (jb job) conflicts(other job) bool {
+	if jb.needsTerminal() && other.needsTerminal() {
+		return true
+	}
+	for _, p := range jb.players {
+		for _, q := range other.players {
+			if p == q {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run the jobs on up to the given number of goroutines. Jobs that conflict run in their
+// order in the list, so the result is the same as running all jobs one by one; the others
+// run concurrently. Results of sessions are returned in the order of the jobs, nil for
+// tournaments, with the failures of the jobs; a failed job doesn't stop the others.
+func runJobs(jobs []job, workers int) ([]*sessionResult, []error) {
+	results := make([]*sessionResult, len(jobs))
+	errs := make([]error, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range jobs {
+		// wait for every earlier job in conflict with this one
+		var deps []chan struct{}
+		for k := 0; k < i; k++ {
+			if jobs[i].conflicts(jobs[k]) {
+				deps = append(deps, done[k])
+			}
+		}
+		wg.Add(1)
+		go func(i int, deps []chan struct{}) {
+			defer wg.Done()
+			defer close(done[i])
+			for _, d := range deps {
+				<-d
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i], errs[i] = jobs[i].run()
+		}(i, deps)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// print the results of the sessions of a batch run in their order
+func printBatchSummary(jobs []job, results []*sessionResult, errs []error) {
+	fmt.Print("*** Batch summary *** \n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tplayers\twins\tdraws\t")
+	for i, jb := range jobs {
+		r := results[i]
+		if r == nil {
+			fmt.Fprintf(w, "%v\ttournament of %v players\t\t\t", i, len(jb.players))
+		} else {
+			fmt.Fprintf(w, "%v\t%v / %v\t%v / %v\t%v\t", i, jb.players[0].name, jb.players[1].name, r.wins[0], r.wins[1], r.draws)
+		}
+		if errs[i] != nil { // the failures of a tournament's sessions are on a line each
+			fmt.Fprintf(w, "failed: %v", strings.ReplaceAll(errs[i].Error(), "\n", "; "))
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Println()
+	return
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// a failed job doesn't stop the others, even those waiting for it, and its failure is kept
+// in the order of the jobs
+func TestRunJobsFailure(t *testing.T) {
+	p := &player{name: "p", agent: &stubAgent{}}
+	failed := errors.New("engine crashed")
+	var ran []int
+	jobs := []job{
+		{players: []*player{p}, run: func() (*sessionResult, error) { ran = append(ran, 0); return &sessionResult{}, failed }},
+		{players: []*player{p}, run: func() (*sessionResult, error) { ran = append(ran, 1); return &sessionResult{draws: 1}, nil }},
+	}
+	results, errs := runJobs(jobs, 2)
+	if len(ran) != 2 || ran[0] != 0 || ran[1] != 1 {
+		t.Errorf("jobs ran in order %v, want [0 1]", ran)
+	}
+	if errs[0] != failed || errs[1] != nil {
+		t.Errorf("got failures %v, want [%v <nil>]", errs, failed)
+	}
+	if results[1] == nil || results[1].draws != 1 {
+		t.Errorf("result of the second job is %v", results[1])
+	}
+}
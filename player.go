@@ -2,39 +2,101 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
-type stateCounts map[int64]uint            // each state maps to how many times it's encountered
-type stateValues map[int64]float64         // each state maps to a value
-type stateValueHistory map[int64][]float64 // each state maps to an array of values
+// agent is the mind of a player: it chooses the player's moves and learns from the episodes
+type agent interface {
+	kind() string                                // kind of agent, e.g. "robot" or "human"
+	startSession(verb bool)                      // prepare for a new session; verb sets the agent to verbose
+	act(env environment, symbol string) location // choose the location to move to
+	observe(env environment, symbol string)      // see the board following each move
+	learn(env environment, symbol string)        // learn at the end of an episode
+	export(p *player) error                      // write out what the agent has learnt at the end of a session
+}
 
-type robotSpecs struct {
-	alp float64 // learning rate; if zero, use weighted average to update the value
-	eps float64 // epsilon-greedy search
-	gam float64 // discount factor
+// failingAgent is an agent that can fail during a session, e.g. an engine that crashes. Its
+// failure abandons the episode, and ends the session.
+type failingAgent interface {
+	failure() error // failure in the session, nil if none; startSession clears it
 }
 
-type mind struct {
-	specs    robotSpecs
-	counts   stateCounts       // count number of times each state has appeared
-	demohist stateValueHistory // historic values of demo states in the robot's record
-	values   stateValues       // most updated values of the robot's known states
-	verb     bool              // verbose
+// forgettingAgent is an agent that keeps track of the episode going on, and forgets it when
+// the episode is abandoned without a result
+type forgettingAgent interface {
+	forget()
 }
 
 type player struct {
-	name    string  // name of the player
-	symbol  string  // "x" plays first, "o" plays second. Each episode assigns symbols randomly.
-	being   string  // human or robot
-	history []int64 // history of states played in the episode
-	wins    int     // number of wins
-	mind    mind    // empty if human
+	name   string // name of the player
+	symbol string // "x" plays first, "o" plays second. Each episode assigns symbols randomly.
+	wins   int    // number of wins
+	draws  int    // number of draws
+	losses int    // number of losses
+	agent  agent  // chooses the moves and learns from them
+}
+
+type playerPair [2]*player
+
+// check whether a human is in the pair
+func (ps *playerPair) hasHuman() bool {
+	return ps[0].agent.kind() == "human" || ps[1].agent.kind() == "human"
+}
+
+// failure of an agent of the pair, which ends the session; nil if none
+func (ps *playerPair) failure() error {
+	for _, p := range ps {
+		if fa, ok := p.agent.(failingAgent); ok && fa.failure() != nil {
+			return fmt.Errorf("%v failed: %v", p.name, fa.failure())
+		}
+	}
+	return nil
+}
+
+// check whether the episode going on is abandoned, as an agent has failed or quit
+func (ps *playerPair) abandons() bool {
+	return ps.failure() != nil || ps.quit()
+}
+
+// quittingAgent is an agent that can quit a session, e.g. a human leaving the terminal UI. Its
+// quitting abandons the episode, and stops the session.
+type quittingAgent interface {
+	quit() bool // the agent has quit the session; startSession clears it
+}
+
+// check whether an agent of the pair has quit the session
+func (ps *playerPair) quit() bool {
+	for _, p := range ps {
+		if qa, ok := p.agent.(quittingAgent); ok && qa.quit() {
+			return true
+		}
+	}
+	return false
 }
 
-type playerPair [2]player
+// terminal UI of a human of the pair, which shows the plan boards of the other agent; nil if
+// there's none
+func (ps *playerPair) terminal() *terminal {
+	for _, p := range ps {
+		if h, ok := p.agent.(*human); ok && h.tui != nil {
+			return h.tui
+		}
+	}
+	return nil
+}
+
+// check whether a human is among the players
+func hasHuman(players []player) bool {
+	for _, p := range players {
+		if p.agent.kind() == "human" {
+			return true
+		}
+	}
+	return false
+}
 
 func createPlayers() []player {
 	// number of players
@@ -50,8 +112,7 @@ func createPlayers() []player {
 	// define each player
 	players := make([]player, N)
 	for i := range players {
-		var name string
-		var isRobot bool
+		var name, being string
 		// name
 		for {
 			fmt.Printf("Enter name of player #%v: ", i)
@@ -62,239 +123,202 @@ func createPlayers() []player {
 		}
 		// being
 		for {
-			fmt.Printf("robot? (t/f): ")
-			_, err := fmt.Scanf("%t", &isRobot)
-			if err == nil {
+			fmt.Printf("kind (robot/human/minimax/mcts/external): ")
+			_, err := fmt.Scanf("%s", &being)
+			if err == nil && (being == "robot" || being == "human" || being == "minimax" || being == "mcts" || being == "external") {
 				break
 			}
 		}
-		if isRobot {
-			// specs
-			var a, e, g float64
-			fmt.Printf("specs (alp eps gam) / click enter to use default values (%v %v %v): ", alpha, epsilon, gamma)
-			_, err := fmt.Scanf("%f%f%f", &a, &e, &g)
-			if err != nil {
-				a, e, g = alpha, epsilon, gamma
-				fmt.Printf("use default specs \n")
+		switch being {
+		case "robot":
+			players[i] = promptRobot(name)
+		case "minimax":
+			var depth int
+			fmt.Printf("search depth / click enter to search to the end: ")
+			_, err := fmt.Scanf("%d", &depth)
+			if err != nil || depth < 0 {
+				depth = 0
+			}
+			players[i] = player{name: name, agent: newMinimax(name, depth)}
+		case "mcts":
+			players[i] = promptMCTS(name)
+		case "external":
+			var command []string
+			for len(command) == 0 {
+				fmt.Printf("command line of the engine: ")
+				command = strings.Fields(scanLine())
 			}
-			players[i].initializeRobot(name, robotSpecs{alp: a, eps: e, gam: g}, false)
-		} else {
-			players[i].initializeHuman(name)
+			players[i] = player{name: name, agent: newExternal(name, command)}
+		default:
+			players[i] = player{name: name, agent: &human{}}
 		}
 	}
 	fmt.Print("*** Done creating players *** \n\n")
 	return players
 }
 
-func (p *player) initializeRobot(name string, rs robotSpecs, verb bool) {
-	p.name = name
-	p.symbol = ""
-	p.being = "robot"
-	p.history = []int64{}
-	p.wins = 0
-	p.mind.specs = rs
-	p.mind.counts = stateCounts{}
-	p.mind.demohist = stateValueHistory{}
-	p.mind.values = stateValues{}
-	p.mind.verb = verb
-	return
-}
-
-func (p *player) initializeHuman(name string) {
-	p.name = name
-	p.symbol = ""
-	p.being = "human"
-	p.history = []int64{}
-	p.wins = 0
-	p.mind = mind{}
-	return
-}
-
-// resetHistory resets the state history of a player
-func (p *player) resetHistory() {
-	p.history = []int64{}
-	return
-}
-
-// append the new state to the player's state history within the episode
-func (p *player) updateStateSequence(state int64) {
-	p.history = append(p.history, state)
-	return
-}
-
-func (p *player) getDemoStates() {
-	if p.being == "robot" {
-		for i := len(p.history) - 1; i > len(p.history)-(1+nDemoStates); i-- {
-			state := p.history[i]
-			p.mind.demohist[state] = []float64{}
+// create a robot from a model file or from specs entered by the user
+func promptRobot(name string) player {
+	var p player
+	// model
+	for {
+		var filename string
+		fmt.Printf("model file / click enter to start a new robot: ")
+		_, err := fmt.Scanf("%s", &filename)
+		if err != nil {
+			break
 		}
+		err = p.loadModel(name, filename)
+		if err == nil {
+			return p
+		}
+		fmt.Printf("%v \n", err)
 	}
-	return
-}
-
-func (p *player) playerActs(env environment) (actionLocation location) {
-	if p.being == "robot" {
-		return p.robotActs(env)
-	} else if p.being == "human" {
-		return p.humanActs(env)
+	// specs
+	var algo, as, es string
+	var a, e schedule
+	var g, l float64
+	var sym bool
+	var x exploration
+	fmt.Printf("algorithm (%v/%v/%v) / click enter to use %v: ", monteCarlo, temporalDifference, qLearning, monteCarlo)
+	_, err := fmt.Scanf("%s", &algo)
+	if err != nil || (algo != temporalDifference && algo != qLearning) {
+		algo = monteCarlo
+	}
+	if algo == temporalDifference {
+		fmt.Printf("lambda / click enter to use TD(0): ")
+		_, err = fmt.Scanf("%f", &l)
+		if err != nil || l < 0 || l > 1 {
+			l = 0
+		}
 	}
-	fmt.Printf("player %v is an unknown creature; the game board explodes \n", p.name)
-	os.Exit(1)
-	return
-}
-
-func (p *player) humanActs(env environment) (actionLocation location) {
-	printBoard(&env.board, true)
 	for {
-		var x, y int
-		fmt.Print("Enter location (x y): ")
-		_, err := fmt.Scanf("%d%d", &x, &y)
+		fmt.Printf("specs (alp eps gam), alp and eps may be schedules like exp:0.3:0.999 / click enter to use default values (%v %v %v): ", alpha, epsilon, gamma)
+		_, err = fmt.Scanf("%s%s%f", &as, &es, &g)
+		if err != nil {
+			a, e, g = constantSchedule(alpha), constantSchedule(epsilon), gamma
+			fmt.Printf("use default specs \n")
+			break
+		}
+		a, err = parseSchedule(as)
 		if err == nil {
-			l := location{x, y}
-			if env.board[l[0]][l[1]] == "" {
-				fmt.Printf("you are making a move to %v \n", l)
-				return l
-			}
+			e, err = parseSchedule(es)
 		}
-		// invalid move, re-enter location
-		fmt.Print("invalid move \n")
-	}
-}
-
-// determine what location the robot moves to
-func (p *player) robotActs(env environment) (actionLocation location) {
-	if rand.Float64() < p.mind.specs.eps {
-		// take a random action
-		possibleLocations := []location{}
-		for irow, row := range env.board {
-			for ielement, element := range row {
-				if element == "" {
-					possibleLocations = append(possibleLocations, location{irow, ielement})
-				}
-			}
+		if err == nil && (g < 0 || g > 1) {
+			err = fmt.Errorf("gam %v is out of [0, 1]", g)
 		}
-		pickedIndex := rand.Intn(len(possibleLocations))
-		actionLocation = possibleLocations[pickedIndex]
-		if p.mind.verb || printSteps {
-			fmt.Printf("player %v(%v)'s takes action randomly at %v \n", p.name, p.symbol, actionLocation)
+		if err == nil {
+			break
 		}
-	} else {
-		plan := make(board, boardSize) // only useful for printing out the plan
-		// choose the best action based on current values of states
-		bestGain := -1.0
-		for irow, row := range env.board {
-			plan[irow] = make([]string, boardSize)
-			for ielement, element := range row {
-				plan[irow][ielement] = element
-				if element == "" { // location is empty; find value if player moves here
-					env.board[irow][ielement] = p.symbol            // board after this move
-					testState := boardToState(&env.board, p.symbol) // state after this move
-					testWinner := getWinner(env.board)              // winner after this move
-					testEmpties := getEmpties(env.board)            // empty spots after this move
-					env.board[irow][ielement] = ""                  // revert this action
-					// get gain of the test state
-					var testGain float64
-					if testWinner != "" || testEmpties == 0 {
-						// test state is final state, reward is non-zero, value is zero
-						testGain = getReward(testWinner, p.symbol)
-					} else {
-						testValue, ok := p.mind.values[testState]
-						if !ok { // there's no record of this state, use default value
-							testValue = defaultValue()
-						}
-						testGain = p.mind.specs.gam * testValue
-					}
-					plan[irow][ielement] = strconv.FormatFloat(testGain, 'f', 2, 64)
-					if testGain > bestGain {
-						bestGain = testGain
-						actionLocation = location{irow, ielement}
-					}
-				}
-			}
+		fmt.Printf("%v \n", err)
+	}
+	for {
+		var text string
+		fmt.Printf("exploration (%v/%v:T/%v:C/%v:V) / click enter to use %v: ", epsGreedy, boltzmann, ucb1, optimistic, epsGreedy)
+		_, err := fmt.Scanf("%s", &text)
+		if err != nil {
+			text = epsGreedy
 		}
-		if p.mind.verb || printSteps {
-			fmt.Printf("player %v(%v)'s plan board: \n", p.name, p.symbol)
-			printBoard(&plan, true)
-			fmt.Printf("player %v(%v) takes action at %v \n", p.name, p.symbol, actionLocation)
+		x, err = parseExploration(text)
+		if err == nil {
+			break
 		}
+		fmt.Printf("%v \n", err)
 	}
-	return actionLocation
-}
-
-// append the state-values learnt in each episode to the player's memory
-func (p *player) updatePlayerRecord(env environment) {
-	if p.symbol == env.winner {
-		p.wins++
+	rw := defaultRewards
+	fmt.Printf("rewards (win draw lose) / click enter to use default values (%v %v %v): ", winReward, drawReward, loseReward)
+	_, err = fmt.Scanf("%f%f%f", &rw.win, &rw.draw, &rw.lose)
+	if err != nil {
+		rw = defaultRewards
 	}
-	if p.being == "robot" {
-		p.updateStateValues(env)
-		p.updateStateValueHistory(env)
-		p.updateStateCounts()
+	fmt.Printf("shaping rewards per threat created, threat blocked and move (threat block move) / click enter to use none: ")
+	_, err = fmt.Scanf("%f%f%f", &rw.threat, &rw.block, &rw.move)
+	if err != nil {
+		rw.threat, rw.block, rw.move = 0, 0, 0
 	}
-	p.resetHistory()
-	return
+	for algo != qLearning {
+		fmt.Printf("learn on canonical states under board symmetries? (t/f): ")
+		_, err := fmt.Scanf("%t", &sym)
+		if err == nil {
+			break
+		}
+	}
+	return player{name: name, agent: createRobot(name, robotSpecs{algo: algo, alp: a, eps: e, gam: g, lam: l, sym: sym, explore: x, rewards: rw})}
 }
 
-// should only be run at the end of an episode
-func (p *player) updateStateValues(env environment) {
-	gains := make(map[int64]float64, len(p.history)) // values learned through this episode
-	finalReward := getReward(env.winner, p.symbol)
-	// loop backward from the last state to the first along history of this episode
-	// i is the index of history array
-	gain := 0.0
-	for i := len(p.history) - 1; i >= 0; i-- {
-		state := p.history[i]
-		var reward float64
-		if i == len(p.history)-2 {
-			reward = finalReward
-		} else {
-			reward = 0.0
+// create an MCTS player with the budget and prior entered by the user
+func promptMCTS(name string) player {
+	var playouts int
+	var budget time.Duration
+	for {
+		var text string
+		fmt.Printf("playouts per move, or time per move like 200ms / click enter to use %v playouts: ", defaultPlayouts)
+		_, err := fmt.Scanf("%s", &text)
+		if err != nil {
+			break
+		}
+		if n, err := strconv.Atoi(text); err == nil && n > 0 {
+			playouts = n
+			break
+		}
+		if d, err := time.ParseDuration(text); err == nil && d > 0 {
+			budget = d
+			break
 		}
-		gain = reward + p.mind.specs.gam*gain
-		gains[state] = gain
 	}
-	// update the state values
-	for state, gain := range gains {
-		if p.mind.specs.alp == 0.0 {
-			// update V by weighted average between new and existing values
-			count, ok := p.mind.counts[state]
-			if !ok {
-				count = 0
-			}
-			p.mind.values[state] = (float64(count)*p.mind.values[state] + gain) / float64(count+1)
-		} else {
-			// update V by correction to the new value with learning rate
-			oldValue, ok := p.mind.values[state]
-			if !ok {
-				oldValue = defaultValue()
-			}
-			p.mind.values[state] = oldValue + p.mind.specs.alp*(gain-oldValue)
+	var prior *robot
+	for {
+		var filename string
+		fmt.Printf("prior model file / click enter to search without prior: ")
+		_, err := fmt.Scanf("%s", &filename)
+		if err != nil {
+			break
+		}
+		prior, err = loadPrior(filename)
+		if err == nil {
+			break
 		}
+		fmt.Printf("%v \n", err)
 	}
-	return
+	rollout := randomRollout
+	if prior != nil {
+		fmt.Printf("rollout (%v/%v) / click enter to use %v: ", randomRollout, valueRollout, randomRollout)
+		_, err := fmt.Scanf("%s", &rollout)
+		if err != nil || rollout != valueRollout {
+			rollout = randomRollout
+		}
+	}
+	return player{name: name, agent: newMCTS(name, playouts, budget, rollout, prior)}
 }
 
-// generate a value of certain mean and certain randomness
-func defaultValue() float64 {
-	return initialValue + fluctuation*(rand.Float64()-0.5)
+// read a line entered by the user; unlike a buffered reader, it reads nothing past the line,
+// which is left to the next Scanf
+func scanLine() string {
+	var line []byte
+	c := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(c)
+		if n == 0 || err != nil || c[0] == '\n' {
+			break
+		}
+		line = append(line, c[0])
+	}
+	return strings.TrimSpace(string(line))
 }
 
-// should be run right after updateStateValues()
-func (p *player) updateStateValueHistory(env environment) {
-	for state := range p.mind.demohist {
-		p.mind.demohist[state] = append(p.mind.demohist[state], p.mind.values[state])
-	}
-	return
+func (p *player) playerActs(env environment) location {
+	return p.agent.act(env, p.symbol)
 }
 
-// update the record of how many times each state has appeared
-func (p *player) updateStateCounts() {
-	for _, state := range p.history {
-		count, ok := p.mind.counts[state]
-		if !ok { // this state appears the first time
-			count = 0
-		}
-		p.mind.counts[state] = count + 1
+// update the player's record and let the agent learn at the end of an episode
+func (p *player) updatePlayerRecord(env environment) {
+	if p.symbol == env.winner {
+		p.wins++
+	} else if env.winner == "" {
+		p.draws++
+	} else {
+		p.losses++
 	}
+	p.agent.learn(env, p.symbol)
 	return
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+)
+
+// action is a move to a location from a state
+type action struct {
+	state stateKey
+	loc   location
+}
+
+type actionCounts map[action]uint    // each action maps to how many times it's taken
+type actionValues map[action]float64 // each action maps to a value
+
+// qRobot learns the values of actions by Q-learning, updating them at every step of an episode
+type qRobot struct {
+	name     string       // name of the player, for printing
+	specs    robotSpecs   // sym is not supported
+	counts   actionCounts // count number of times each action has been taken
+	qvalues  actionValues // most updated values of the robot's known actions
+	verb     bool         // verbose
+	last     *action      // the robot's latest action in the episode, not yet updated
+	shaping  float64      // shaping reward of the latest action
+	episodes int          // number of episodes learnt from, which the schedules run on
+	rng      *rand.Rand   // random source of the robot's exploration and default values
+	plans    *terminal    // terminal UI the verbose robot shows its plan boards in; none if nil
+}
+
+func newQRobot(name string, rs robotSpecs) *qRobot {
+	return &qRobot{
+		name:    name,
+		specs:   rs,
+		counts:  actionCounts{},
+		qvalues: actionValues{},
+		rng:     newRand(),
+	}
+}
+
+func (q *qRobot) kind() string {
+	return "robot"
+}
+
+func (q *qRobot) startSession(verb bool) {
+	q.verb = verb
+	return
+}
+
+// forget the latest action of the abandoned episode, which has no result to learn from
+func (q *qRobot) forget() {
+	q.last, q.shaping = nil, 0
+	return
+}
+
+func (q *qRobot) showPlans(t *terminal) {
+	q.plans = t
+	return
+}
+
+// value of an action; an unknown action takes the default value, or an optimistic one
+func (q *qRobot) qvalue(a action) float64 {
+	value, ok := q.qvalues[a]
+	if !ok {
+		value = q.specs.explore.unknownValue(q.rng)
+	}
+	return value
+}
+
+// find every possible action from the board in the robot's perspective and its value, laid
+// on a plan board
+func (q *qRobot) planActions(b board, symbol string) (actions []action, values []float64, plan board) {
+	state := boardToState(&b, symbol)
+	plan = make(board, len(b)) // only useful for printing out the plan
+	for irow, row := range b {
+		plan[irow] = make([]string, len(row))
+		for ielement, element := range row {
+			plan[irow][ielement] = element
+			if element == "" {
+				a := action{state, location{irow, ielement}}
+				value := q.qvalue(a)
+				plan[irow][ielement] = strconv.FormatFloat(value, 'f', 2, 64)
+				actions = append(actions, a)
+				values = append(values, value)
+			}
+		}
+	}
+	return actions, values, plan
+}
+
+// find the best action from the board in the robot's perspective and its value
+func (q *qRobot) bestAction(b board, symbol string) (action, float64) {
+	actions, values, _ := q.planActions(b, symbol)
+	best := argmax(values)
+	return actions[best], values[best]
+}
+
+// determine what location the robot moves to, by its exploration policy
+func (q *qRobot) act(env environment, symbol string) location {
+	actions, values, plan := q.planActions(env.board, symbol)
+	counts := make([]uint, len(actions))
+	moves := make([]location, len(actions))
+	for i, a := range actions {
+		counts[i] = q.counts[a]
+		moves[i] = a.loc
+	}
+	eps := q.specs.eps.at(q.episodes)
+	probs := q.specs.explore.probabilities(values, counts, eps)
+	a := actions[sample(probs, q.rng)]
+	if q.verb || printSteps {
+		fmt.Printf("player %v(%v)'s plan board: \n", q.name, symbol)
+		printBoard(&plan, true)
+		fmt.Printf("player %v(%v)'s probabilities of moves (%v): \n", q.name, symbol, q.specs.explore)
+		probBoard := probabilityBoard(env.board, moves, probs)
+		printBoard(&probBoard, true)
+		fmt.Printf("player %v(%v) takes action at %v \n", q.name, symbol, a.loc)
+		q.plans.showPlan(fmt.Sprintf("%v(%v)'s plan board", q.name, symbol), plan)
+	}
+	q.last = &a
+	return a.loc
+}
+
+// choose the best action based on current values of actions, and lay the value of every
+// possible action on a plan board
+func (q *qRobot) greedyMove(env environment, symbol string) (location, board) {
+	actions, values, plan := q.planActions(env.board, symbol)
+	return actions[argmax(values)].loc, plan
+}
+
+// when the opponent has replied to the robot's latest action, bootstrap the value of that
+// action from the best action available now
+func (q *qRobot) observe(env environment, symbol string) {
+	if q.last != nil && env.lastMove == q.last.loc {
+		q.shaping = q.specs.rewards.shaping(env.board, env.lastMove, env.spec.k)
+	}
+	if q.last == nil || env.gameOver || nextSymbol(env.board) != symbol {
+		return
+	}
+	_, bestValue := q.bestAction(env.board, symbol)
+	q.updateQValue(*q.last, q.shaping+q.specs.gam*bestValue)
+	q.last = nil
+	return
+}
+
+// the final reward is the target of the robot's last action in the episode
+func (q *qRobot) learn(env environment, symbol string) {
+	if q.last != nil {
+		q.updateQValue(*q.last, q.shaping+q.specs.rewards.reward(env.winner, symbol))
+		q.last = nil
+	}
+	q.episodes++
+	return
+}
+
+// move the value of the action toward the target
+func (q *qRobot) updateQValue(a action, target float64) {
+	count := q.counts[a]
+	alp := q.specs.alp.at(q.episodes)
+	if q.specs.alp.alwaysZero() {
+		// update Q by weighted average between new and existing values
+		q.qvalues[a] = (float64(count)*q.qvalues[a] + target) / float64(count+1)
+	} else {
+		// update Q by correction to the new value with learning rate
+		oldValue := q.qvalue(a)
+		q.qvalues[a] = oldValue + alp*(target-oldValue)
+	}
+	q.counts[a] = count + 1
+	return
+}
+
+// export action values and save the model
+func (q *qRobot) export(p *player) error {
+	fmt.Printf("%v has learnt from %v episodes, now with alp %.4g and eps %.4g \n", p.name, q.episodes, q.specs.alp.at(q.episodes), q.specs.eps.at(q.episodes))
+	exportQValues(p.name, q.qvalues)
+	return q.saveModel(p)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// rating periods: every episode, or every session as a single game scored by the share of
+// points (1 per win, 0.5 per draw) in its episodes
+const (
+	ratePerEpisode = "episode"
+	ratePerSession = "session"
+)
+
+const eloInitial = 1500.0    // Elo rating of a new player
+const eloK = 16.0            // Elo K-factor
+const glickoInitial = 1500.0 // Glicko-2 rating of a new player
+const glickoRD = 350.0       // Glicko-2 rating deviation of a new player
+const glickoVol = 0.06       // Glicko-2 volatility of a new player
+const glickoTau = 0.5        // Glicko-2 constraint on the change of volatility
+const glickoScale = 173.7178 // Glicko-2 scale between the Glicko and the Glicko-2 ratings
+
+// rating of a player in both systems
+type rating struct {
+	Elo    float64 `json:"elo"`
+	Glicko float64 `json:"glicko"` // Glicko-2 rating, on the Glicko scale
+	RD     float64 `json:"rd"`     // Glicko-2 rating deviation, on the Glicko scale
+	Vol    float64 `json:"vol"`    // Glicko-2 volatility
+	Games  int     `json:"games"`  // number of rated games
+}
+
+// ratingBook keeps the ratings of all players by name, and persists them in a file. It's
+// safe for concurrent use by sessions running in parallel.
+type ratingBook struct {
+	mu       sync.Mutex
+	filename string
+	period   string             // ratePerEpisode or ratePerSession
+	Ratings  map[string]*rating `json:"ratings"`
+}
+
+// read the ratings from the file, or start an empty book if the file doesn't exist
+func loadRatings(filename, period string) (*ratingBook, error) {
+	if period != ratePerEpisode && period != ratePerSession {
+		return nil, fmt.Errorf("invalid rating period %q", period)
+	}
+	rb := &ratingBook{filename: filename, period: period, Ratings: map[string]*rating{}}
+	d, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return rb, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(d, rb); err != nil {
+		return nil, fmt.Errorf("cannot read ratings %v: %v", filename, err)
+	}
+	return rb, nil
+}
+
+// write the ratings to the file
+func (rb *ratingBook) save() error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	d, err := json.MarshalIndent(rb, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rb.filename, d, 0644)
+}
+
+// rating of the player, a new one if the player is not rated yet
+func (rb *ratingBook) get(name string) *rating {
+	r, ok := rb.Ratings[name]
+	if !ok {
+		r = &rating{Elo: eloInitial, Glicko: glickoInitial, RD: glickoRD, Vol: glickoVol}
+		rb.Ratings[name] = r
+	}
+	return r
+}
+
+// rate a game between two players, where the first player scores s (1 win, 0.5 draw, 0 loss,
+// or anything between for a session)
+func (rb *ratingBook) rate(name1, name2 string, s float64) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	r1, r2 := rb.get(name1), rb.get(name2)
+	old1, old2 := *r1, *r2
+	r1.updateElo(old2, s)
+	r2.updateElo(old1, 1-s)
+	r1.updateGlicko(old2, s)
+	r2.updateGlicko(old1, 1-s)
+	r1.Games++
+	r2.Games++
+	return
+}
+
+// update the Elo rating by a game against the opponent
+func (r *rating) updateElo(opp rating, s float64) {
+	expected := 1 / (1 + math.Pow(10, (opp.Elo-r.Elo)/400))
+	r.Elo += eloK * (s - expected)
+	return
+}
+
+// update the Glicko-2 rating by a rating period of a single game against the opponent
+func (r *rating) updateGlicko(opp rating, s float64) {
+	r.updateGlickoPeriod([]rating{opp}, []float64{s})
+	return
+}
+
+// update the Glicko-2 rating by a rating period of games against the opponents, scoring s[j]
+// against opps[j], following Glickman's "Example of the Glicko-2 system"
+func (r *rating) updateGlickoPeriod(opps []rating, s []float64) {
+	mu, phi := (r.Glicko-glickoInitial)/glickoScale, r.RD/glickoScale
+
+	var vInv, sum float64 // 1/v, and the sum of g*(s-e) over the games
+	for j, opp := range opps {
+		muj, phij := (opp.Glicko-glickoInitial)/glickoScale, opp.RD/glickoScale
+		g := 1 / math.Sqrt(1+3*phij*phij/(math.Pi*math.Pi))
+		e := 1 / (1 + math.Exp(-g*(mu-muj)))
+		vInv += g * g * e * (1 - e)
+		sum += g * (s[j] - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	// new volatility by the Illinois algorithm
+	a := math.Log(r.Vol * r.Vol)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-v-ex)/(2*math.Pow(phi*phi+v+ex, 2)) - (x-a)/(glickoTau*glickoTau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	vol := math.Exp(illinois(f, A, B) / 2)
+
+	// new rating deviation and rating
+	phiStar := math.Sqrt(phi*phi + vol*vol)
+	phiNew := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	muNew := mu + phiNew*phiNew*sum
+
+	r.Glicko = muNew*glickoScale + glickoInitial
+	r.RD = phiNew * glickoScale
+	r.Vol = vol
+	return
+}
+
+// root of f between A and B, where f changes sign, by the Illinois algorithm of step 5 of
+// Glickman's example
+func illinois(f func(float64) float64, A, B float64) float64 {
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > 1e-6 {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return A
+}
+
+// print all rated players, the highest Glicko-2 rating first
+func (rb *ratingBook) printLeaderboard() {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	names := make([]string, 0, len(rb.Ratings))
+	for name := range rb.Ratings {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rb.Ratings[names[i]], rb.Ratings[names[j]]
+		if ri.Glicko != rj.Glicko {
+			return ri.Glicko > rj.Glicko
+		}
+		return names[i] < names[j]
+	})
+	fmt.Printf("*** Leaderboard (rated per %v, saved in %v) *** \n", rb.period, rb.filename)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "rank\tplayer\tglicko-2\t± 2 RD\telo\tgames\t")
+	for i, name := range names {
+		r := rb.Ratings[name]
+		fmt.Fprintf(w, "%v\t%v\t%.0f\t%.0f\t%.0f\t%v\t\n", i+1, name, r.Glicko, 2*r.RD, r.Elo, r.Games)
+	}
+	w.Flush()
+	fmt.Println()
+	return
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// Glickman's worked example in "Example of the Glicko-2 system", with tau 0.5
+func TestUpdateGlickoPeriod(t *testing.T) {
+	r := rating{Glicko: 1500, RD: 200, Vol: 0.06}
+	opps := []rating{{Glicko: 1400, RD: 30}, {Glicko: 1550, RD: 100}, {Glicko: 1700, RD: 300}}
+	r.updateGlickoPeriod(opps, []float64{1, 0, 0})
+	if math.Abs(r.Glicko-1464.06) > 0.01 || math.Abs(r.RD-151.52) > 0.01 || math.Abs(r.Vol-0.05999) > 0.00001 {
+		t.Errorf("rating %.2f, RD %.2f, volatility %.5f; want 1464.06, 151.52, 0.05999", r.Glicko, r.RD, r.Vol)
+	}
+}
+
+// a single game is a rating period of one game
+func TestUpdateGlicko(t *testing.T) {
+	r1 := rating{Glicko: 1500, RD: 200, Vol: 0.06}
+	r2 := r1
+	opp := rating{Glicko: 1400, RD: 30}
+	r1.updateGlicko(opp, 0.5)
+	r2.updateGlickoPeriod([]rating{opp}, []float64{0.5})
+	if r1 != r2 {
+		t.Errorf("updateGlicko gives %+v, updateGlickoPeriod %+v", r1, r2)
+	}
+}
+
+// an exact root found on the way is the result, rather than an endpoint
+func TestIllinoisExactRoot(t *testing.T) {
+	if x := illinois(func(x float64) float64 { return 2 - x }, 0, 4); math.Abs(x-2) > 1e-6 {
+		t.Errorf("root %v, want 2", x)
+	}
+}
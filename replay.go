@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+const nListGames = 20 // number of latest games listed by the replay viewer
+
+// read every game record of a game log
+func readGameLog(filename string) ([]gameRecord, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var games []gameRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var gr gameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &gr); err != nil {
+			return nil, fmt.Errorf("cannot read game log %v, line %v: %v", filename, line, err)
+		}
+		if err := gr.validate(); err != nil {
+			return nil, fmt.Errorf("invalid game in %v, line %v: %v", filename, line, err)
+		}
+		games = append(games, gr)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+// spec of the board the game was played on
+func (gr *gameRecord) spec() boardSpec {
+	return boardSpec{rows: gr.Rows, cols: gr.Cols, k: gr.K}
+}
+
+// check that the moves of the game can be replayed on its board
+func (gr *gameRecord) validate() error {
+	if err := gr.spec().validate(); err != nil {
+		return err
+	}
+	var env environment
+	env.initializeEnvironment(gr.spec())
+	for i, loc := range gr.Moves {
+		if env.gameOver {
+			return fmt.Errorf("move #%v is after the end of the game", i+1)
+		}
+		if loc[0] < 0 || loc[0] >= gr.Rows || loc[1] < 0 || loc[1] >= gr.Cols || env.board[loc[0]][loc[1]] != "" {
+			return fmt.Errorf("move #%v at %v is not on an empty location", i+1, loc)
+		}
+		env.updateGameStatus(loc, nextSymbol(env.board))
+	}
+	return nil
+}
+
+// the environment after the first n moves of the game
+func (gr *gameRecord) position(n int) environment {
+	var env environment
+	env.initializeEnvironment(gr.spec())
+	for _, loc := range gr.Moves[:n] {
+		env.updateGameStatus(loc, nextSymbol(env.board))
+	}
+	return env
+}
+
+// replay the games of a game log in the terminal; the plan boards of the robot in the model
+// file, if any, are shown at each position
+func replay(filename, robotFile string) error {
+	games, err := readGameLog(filename)
+	if err != nil {
+		return err
+	}
+	if len(games) == 0 {
+		return fmt.Errorf("game log %v has no game", filename)
+	}
+	var p player
+	var g greedyAgent
+	if robotFile != "" {
+		if err := p.loadModel("", robotFile); err != nil {
+			return err
+		}
+		var ok bool
+		if g, ok = p.agent.(greedyAgent); !ok {
+			return fmt.Errorf("%v is a %v, which has no plan board", p.name, p.agent.kind())
+		}
+	}
+
+	for {
+		// pick a game
+		fmt.Printf("*** %v has %v games; the latest are: *** \n", filename, len(games))
+		for i := len(games) - 1; i >= 0 && i >= len(games)-nListGames; i-- {
+			gr := games[i]
+			fmt.Printf("#%v episode %v on a %v board: %v(x) vs %v(o), %v in %v moves \n", i, gr.Episode, gr.spec(), gr.X.Name, gr.O.Name, gr.Result, len(gr.Moves))
+		}
+		var i int
+		fmt.Printf("pick a game (#) / click enter to quit: ")
+		if _, err := fmt.Scanf("%d", &i); err != nil {
+			break
+		}
+		if i < 0 || i >= len(games) {
+			continue
+		}
+		replayGame(&games[i], &p, g)
+	}
+
+	return nil
+}
+
+// step through the moves of the game forward and backward
+func replayGame(gr *gameRecord, p *player, g greedyAgent) {
+	names := map[string]string{"x": gr.X.Name, "o": gr.O.Name}
+	n := 0 // number of moves played
+	for {
+		env := gr.position(n)
+		fmt.Printf("\n*** %v(x) vs %v(o), move %v of %v *** \n", gr.X.Name, gr.O.Name, n, len(gr.Moves))
+		if n > 0 {
+			last := gr.Moves[n-1]
+			fmt.Printf("%v(%v) played at %v \n", names[env.board[last[0]][last[1]]], env.board[last[0]][last[1]], last)
+		}
+		printBoard(&env.board, true)
+		if n == len(gr.Moves) {
+			if gr.Result == "draw" {
+				fmt.Print("Game Over - draw \n")
+			} else {
+				fmt.Printf("Game Over - %v is the winner \n", names[gr.Result])
+			}
+		} else if g != nil {
+			// the robot's plan in the place of the player to move
+			symbol := nextSymbol(env.board)
+			loc, plan := g.greedyMove(env, symbol)
+			fmt.Printf("%v's plan board as %v(%v): \n", p.name, names[symbol], symbol)
+			printBoard(&plan, true)
+			played := gr.Moves[n]
+			if loc == played {
+				fmt.Printf("%v would play at %v too \n", p.name, loc)
+			} else {
+				fmt.Printf("%v would play at %v (%v), not at %v (%v) \n", p.name, loc, plan[loc[0]][loc[1]], played, plan[played[0]][played[1]])
+			}
+		}
+
+		// step
+		var cmd string
+		fmt.Printf("next (n) / previous (p) / go to move (#) / quit (q); click enter for next: ")
+		if _, err := fmt.Scanf("%s", &cmd); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return // the input is closed
+		} else if err != nil {
+			cmd = "n"
+		}
+		switch cmd {
+		case "n":
+			if n < len(gr.Moves) {
+				n++
+			}
+		case "p":
+			if n > 0 {
+				n--
+			}
+		case "q":
+			return
+		default:
+			if m, err := strconv.Atoi(cmd); err == nil && m >= 0 && m <= len(gr.Moves) {
+				n = m
+			}
+		}
+	}
+}
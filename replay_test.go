@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// the replay ends when its input is closed, rather than stepping on forever
+func TestReplayGameClosedInput(t *testing.T) {
+	gr := &gameRecord{
+		Rows: 3, Cols: 3, K: 3, X: recordPlayer{Name: "a"}, O: recordPlayer{Name: "b"},
+		Moves: []location{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}, Result: "x",
+	}
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = devNull, devNull
+	defer func() { os.Stdin, os.Stdout = stdin, stdout }()
+
+	done := make(chan struct{})
+	go func() {
+		replayGame(gr, nil, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the replay goes on with its input closed")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// greedyAgent can tell the move it would choose without exploring, with its plan board
+type greedyAgent interface {
+	greedyMove(env environment, symbol string) (location, board)
+}
+
+// a position where the player to move chose a move of the given score, and the best move
+// scored bestScore, as scored by minimax
+type positionReport struct {
+	board       board
+	symbol      string // player to move
+	chosen      location
+	chosenScore float64
+	best        location
+	bestScore   float64
+}
+
+// how much the chosen move lost: the drop in game-theoretic outcome, then the drop in score
+func (pr positionReport) severity() (int, float64) {
+	return outcome(pr.bestScore) - outcome(pr.chosenScore), pr.bestScore - pr.chosenScore
+}
+
+type optimalityStats struct {
+	positions    int // positions where the player is to move
+	matches      int // positions where the chosen move has the optimal outcome
+	winBlunders  int // positions where a win is turned into a draw or a loss
+	drawBlunders int // positions where a draw is turned into a loss
+}
+
+// game-theoretic outcome of a minimax score: 1 win, 0 draw, -1 loss
+func outcome(score float64) int {
+	if score >= 1 {
+		return 1
+	} else if score <= -1 {
+		return -1
+	}
+	return 0
+}
+
+func outcomeName(score float64) string {
+	return [3]string{"loss", "draw", "win"}[outcome(score)+1]
+}
+
+// enumerate every reachable position of the board and compare the player's greedy move in
+// it with the optimal moves found by a perfect minimax player; practical on small boards only
+func reportOptimality(p *player, spec boardSpec) error {
+	g, ok := p.agent.(greedyAgent)
+	if !ok {
+		return fmt.Errorf("%v is a %v, which has no greedy move", p.name, p.agent.kind())
+	}
+	oracle := newMinimax("oracle", 0)
+	stats := map[string]*optimalityStats{"x": {}, "o": {}}
+	var blunders []positionReport
+	visited := map[stateKey]bool{}
+
+	var env environment
+	env.initializeEnvironment(spec)
+	var visit func(symbol string)
+	visit = func(symbol string) {
+		state := boardToState(&env.board, symbol)
+		if visited[state] {
+			return
+		}
+		visited[state] = true
+
+		// compare the greedy move with the optimal ones
+		scores := oracle.moveScores(env.board, spec, symbol)
+		pr := positionReport{symbol: symbol}
+		pr.chosen, _ = g.greedyMove(env, symbol)
+		pr.chosenScore = scores[pr.chosen]
+		moves := orderedMoves(env.board)
+		pr.best, pr.bestScore = moves[0], scores[moves[0]]
+		for _, loc := range moves {
+			if scores[loc] > pr.bestScore {
+				pr.best, pr.bestScore = loc, scores[loc]
+			}
+		}
+		st := stats[symbol]
+		st.positions++
+		if d, _ := pr.severity(); d == 0 {
+			st.matches++
+		} else {
+			if outcome(pr.bestScore) == 1 {
+				st.winBlunders++
+			} else {
+				st.drawBlunders++
+			}
+			pr.board = make(board, len(env.board))
+			for irow := range env.board {
+				pr.board[irow] = append([]string{}, env.board[irow]...)
+			}
+			blunders = append(blunders, pr)
+		}
+
+		// go on to the positions following each move
+		for _, loc := range moves {
+			env.board[loc[0]][loc[1]] = symbol
+			if !isWinningMove(env.board, loc, spec.k) && getEmpties(env.board) > 0 {
+				visit(opponentSymbol(symbol))
+			}
+			env.board[loc[0]][loc[1]] = ""
+		}
+	}
+	visit("x")
+
+	// summary
+	fmt.Printf("*** Optimality of %v on a %v board *** \n", p.name, spec)
+	for _, symbol := range []string{"x", "o"} {
+		st := stats[symbol]
+		fmt.Printf("as %v: %v positions, optimal move in %v (%.1f%%), wins blundered into draws/losses in %v, draws blundered into losses in %v \n",
+			symbol, st.positions, st.matches, 100*float64(st.matches)/float64(st.positions), st.winBlunders, st.drawBlunders)
+	}
+
+	// worst positions
+	sort.SliceStable(blunders, func(i, j int) bool {
+		di, si := blunders[i].severity()
+		dj, sj := blunders[j].severity()
+		if di != dj {
+			return di > dj
+		}
+		return si > sj
+	})
+	if len(blunders) > nWorstPositions {
+		blunders = blunders[:nWorstPositions]
+	}
+	if len(blunders) > 0 {
+		fmt.Printf("\n*** %v worst positions *** \n", len(blunders))
+	}
+	for _, pr := range blunders {
+		fmt.Printf("\n%v to move: %v moves to %v (%v), best is %v (%v) \n",
+			pr.symbol, p.name, pr.chosen, outcomeName(pr.chosenScore), pr.best, outcomeName(pr.bestScore))
+		printBoard(&pr.board, true)
+	}
+	return nil
+}
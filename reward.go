@@ -0,0 +1,74 @@
+package main
+
+// rewardSpecs are the rewards of a robot: at the end of the episode by its outcome, and for
+// each of its moves by shaping terms
+type rewardSpecs struct {
+	win    float64 // reward for winning the game
+	draw   float64 // reward for a draw game
+	lose   float64 // reward for losing the game
+	threat float64 // reward for each threat a move creates: a line of k with k-1 own symbols and an empty location
+	block  float64 // reward for each of the opponent's threats a move blocks
+	move   float64 // reward for every move; a penalty if negative
+}
+
+// the global rewards, without shaping
+var defaultRewards = rewardSpecs{win: winReward, draw: drawReward, lose: loseReward}
+
+// reward at the end of an episode for the player playing s, by knowing the winner
+func (rw rewardSpecs) reward(w, s string) float64 {
+	if w == s { // this player wins
+		return rw.win
+	} else if w == "" { // draw game
+		return rw.draw
+	}
+	return rw.lose
+}
+
+// check whether any shaping term is set
+func (rw rewardSpecs) shaped() bool {
+	return rw.threat != 0 || rw.block != 0 || rw.move != 0
+}
+
+// shaping reward of the move to loc, just placed on the board
+func (rw rewardSpecs) shaping(b board, loc location, k int) float64 {
+	if !rw.shaped() {
+		return 0
+	}
+	threats, blocks := countThreats(b, loc, k)
+	return rw.move + rw.threat*float64(threats) + rw.block*float64(blocks)
+}
+
+// count the lines of k through loc, just placed on the board, that the move turns into
+// threats of its own, and that were threats of the opponent it blocks
+func countThreats(b board, loc location, k int) (threats, blocks int) {
+	s := b[loc[0]][loc[1]]
+	directions := [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for _, d := range directions {
+		for j := 0; j < k; j++ { // the line starts j locations before loc
+			var own, opponent, empty int
+			inside := true
+			for i := 0; i < k && inside; i++ {
+				irow, icol := loc[0]+(i-j)*d[0], loc[1]+(i-j)*d[1]
+				if irow < 0 || irow >= len(b) || icol < 0 || icol >= len(b[irow]) {
+					inside = false
+				} else if b[irow][icol] == s {
+					own++
+				} else if b[irow][icol] == "" {
+					empty++
+				} else {
+					opponent++
+				}
+			}
+			if !inside || k < 2 {
+				continue
+			}
+			if own == k-1 && empty == 1 {
+				threats++
+			}
+			if opponent == k-1 && own == 1 {
+				blocks++
+			}
+		}
+	}
+	return threats, blocks
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+)
+
+type stateCounts map[stateKey]uint            // each state maps to how many times it's encountered
+type stateValues map[stateKey]float64         // each state maps to a value
+type stateValueHistory map[stateKey][]float64 // each state maps to an array of values
+
+// learning algorithms of robots
+const (
+	monteCarlo         = "mc" // Monte-Carlo update of state values at the end of each episode
+	temporalDifference = "td" // TD(lambda) update of state values at each step
+	qLearning          = "q"  // Q-learning of action values at each step
+)
+
+type robotSpecs struct {
+	algo    string      // learning algorithm, monteCarlo if empty
+	alp     schedule    // learning rate; if zero, use weighted average to update the value
+	eps     schedule    // epsilon-greedy search
+	gam     float64     // discount factor
+	lam     float64     // decay of eligibility traces for temporalDifference; TD(0) if zero
+	sym     bool        // learn on canonical states, so all rotations/reflections of a board share a value
+	explore exploration // exploration policy; eps only applies to epsGreedy
+	rewards rewardSpecs // rewards of the outcomes and shaping terms
+}
+
+type mind struct {
+	specs    robotSpecs
+	counts   stateCounts       // count number of times each state has appeared
+	demohist stateValueHistory // historic values of demo states in the robot's record
+	values   stateValues       // most updated values of the robot's known states
+	episodes int               // number of episodes learnt from, which the schedules run on
+	verb     bool              // verbose
+}
+
+// robot learns the values of states by the Monte-Carlo method or by temporal difference
+type robot struct {
+	name     string     // name of the player, for printing
+	mind     mind       // what the robot has learnt
+	history  []stateKey // history of states played in the episode
+	shaping  []float64  // shaping reward of the move into each state of history; zero for the opponent's moves
+	pickDemo bool       // pick the demo states at the end of the first episode of a session
+	rng      *rand.Rand // random source of the robot's exploration and default values
+	plans    *terminal  // terminal UI the verbose robot shows its plan boards in; none if nil
+}
+
+// create a robot learning with the algorithm of its specs
+func createRobot(name string, rs robotSpecs) agent {
+	if rs.algo == qLearning {
+		return newQRobot(name, rs)
+	}
+	return newRobot(name, rs)
+}
+
+func newRobot(name string, rs robotSpecs) *robot {
+	return &robot{
+		name: name,
+		mind: mind{
+			specs:    rs,
+			counts:   stateCounts{},
+			demohist: stateValueHistory{},
+			values:   stateValues{},
+		},
+		history: []stateKey{},
+		rng:     newRand(),
+	}
+}
+
+func (r *robot) kind() string {
+	return "robot"
+}
+
+func (r *robot) startSession(verb bool) {
+	r.mind.verb = verb
+	r.pickDemo = true
+	return
+}
+
+// forget the history of the abandoned episode
+func (r *robot) forget() {
+	r.resetHistory()
+	return
+}
+
+func (r *robot) showPlans(t *terminal) {
+	r.plans = t
+	return
+}
+
+// update state history following each move, and learn from the step by temporal difference
+func (r *robot) observe(env environment, symbol string) {
+	// The same board is encoded differently by the two players;
+	// each location is viewed not as "x" or "o", but instead as Me or You.
+	r.updateStateSequence(r.encodeState(&env.board, symbol))
+	var shaping float64
+	if env.board[env.lastMove[0]][env.lastMove[1]] == symbol {
+		shaping = r.mind.specs.rewards.shaping(env.board, env.lastMove, env.spec.k)
+	}
+	r.shaping = append(r.shaping, shaping)
+	if r.mind.specs.algo == temporalDifference {
+		r.updateStateValuesTD(env, symbol)
+	}
+	return
+}
+
+// append the state-values learnt in the episode to the robot's memory
+func (r *robot) learn(env environment, symbol string) {
+	if r.pickDemo {
+		r.getDemoStates()
+		r.pickDemo = false
+	}
+	if r.mind.specs.algo != temporalDifference {
+		r.updateStateValues(env, symbol)
+	}
+	r.updateStateValueHistory(env)
+	r.updateStateCounts()
+	r.resetHistory()
+	r.mind.episodes++
+	return
+}
+
+// export values and save the model
+func (r *robot) export(p *player) error {
+	fmt.Printf("%v has learnt from %v episodes, now with alp %.4g and eps %.4g \n", p.name, r.mind.episodes, r.alp(), r.eps())
+	exportValues(p.name, r.mind.values, r.mind.specs.sym)
+	exportValueHistory(p.name, r.mind.demohist)
+	return r.saveModel(p)
+}
+
+// resetHistory resets the state history of a robot
+func (r *robot) resetHistory() {
+	r.history = []stateKey{}
+	r.shaping = []float64{}
+	return
+}
+
+// encode the board into a state in the perspective of the robot playing symbol
+func (r *robot) encodeState(b *board, symbol string) stateKey {
+	state := boardToState(b, symbol)
+	if r.mind.specs.sym {
+		state = state.canonical()
+	}
+	return state
+}
+
+// append the new state to the robot's state history within the episode
+func (r *robot) updateStateSequence(state stateKey) {
+	r.history = append(r.history, state)
+	return
+}
+
+func (r *robot) getDemoStates() {
+	for i := len(r.history) - 1; i > len(r.history)-(1+nDemoStates) && i >= 0; i-- {
+		state := r.history[i]
+		r.mind.demohist[state] = []float64{}
+	}
+	return
+}
+
+// determine what location the robot moves to, by its exploration policy
+func (r *robot) act(env environment, symbol string) location {
+	moves, states, gains, plan := r.planMoves(env, symbol)
+	counts := make([]uint, len(states))
+	for i, state := range states {
+		counts[i] = r.mind.counts[state]
+	}
+	probs := r.mind.specs.explore.probabilities(gains, counts, r.eps())
+	actionLocation := moves[sample(probs, r.rng)]
+	if r.mind.verb || printSteps {
+		fmt.Printf("player %v(%v)'s plan board: \n", r.name, symbol)
+		printBoard(&plan, true)
+		fmt.Printf("player %v(%v)'s probabilities of moves (%v): \n", r.name, symbol, r.mind.specs.explore)
+		probBoard := probabilityBoard(env.board, moves, probs)
+		printBoard(&probBoard, true)
+		fmt.Printf("player %v(%v) takes action at %v \n", r.name, symbol, actionLocation)
+		r.plans.showPlan(fmt.Sprintf("%v(%v)'s plan board", r.name, symbol), plan)
+	}
+	return actionLocation
+}
+
+// choose the best action based on current values of states, and lay the gain of every
+// possible action on a plan board
+func (r *robot) greedyMove(env environment, symbol string) (location, board) {
+	moves, _, gains, plan := r.planMoves(env, symbol)
+	return moves[argmax(gains)], plan
+}
+
+// find every possible action, the state it leads to and its gain based on current values of
+// states, and lay the gains on a plan board
+func (r *robot) planMoves(env environment, symbol string) (moves []location, states []stateKey, gains []float64, plan board) {
+	plan = make(board, len(env.board)) // only useful for printing out the plan
+	for irow, row := range env.board {
+		plan[irow] = make([]string, len(row))
+		for ielement, element := range row {
+			plan[irow][ielement] = element
+			if element == "" { // location is empty; find value if player moves here
+				env.board[irow][ielement] = symbol             // board after this move
+				testState := r.encodeState(&env.board, symbol) // state after this move
+				testWinner := getWinner(env.board, env.spec.k) // winner after this move
+				testEmpties := getEmpties(env.board)           // empty spots after this move
+				// shaping reward of this move
+				testGain := r.mind.specs.rewards.shaping(env.board, location{irow, ielement}, env.spec.k)
+				env.board[irow][ielement] = "" // revert this action
+				// get gain of the test state
+				if testWinner != "" || testEmpties == 0 {
+					// test state is final state, reward is non-zero, value is zero
+					testGain += r.mind.specs.rewards.reward(testWinner, symbol)
+				} else {
+					testGain += r.mind.specs.gam * r.stateValue(testState)
+				}
+				plan[irow][ielement] = strconv.FormatFloat(testGain, 'f', 2, 64)
+				moves = append(moves, location{irow, ielement})
+				states = append(states, testState)
+				gains = append(gains, testGain)
+			}
+		}
+	}
+	return moves, states, gains, plan
+}
+
+// should only be run at the end of an episode
+func (r *robot) updateStateValues(env environment, symbol string) {
+	gains := make(map[stateKey]float64, len(r.history)) // values learned through this episode
+	finalReward := r.mind.specs.rewards.reward(env.winner, symbol)
+	// loop backward from the last state to the first along history of this episode
+	// i is the index of history array
+	gain := 0.0
+	for i := len(r.history) - 1; i >= 0; i-- {
+		state := r.history[i]
+		var reward float64 // reward of the move from this state to the next
+		if i == len(r.history)-2 {
+			reward = finalReward
+		} else {
+			reward = 0.0
+		}
+		if i+1 < len(r.shaping) {
+			reward += r.shaping[i+1]
+		}
+		gain = reward + r.mind.specs.gam*gain
+		gains[state] = gain
+	}
+	alp, averaging := r.alp(), r.mind.specs.alp.alwaysZero()
+	// update the state values in the order of history, so a run is reproduced by its seed
+	for _, state := range r.history {
+		gain, ok := gains[state]
+		if !ok { // already updated
+			continue
+		}
+		delete(gains, state)
+		if averaging {
+			// update V by weighted average between new and existing values
+			count, ok := r.mind.counts[state]
+			if !ok {
+				count = 0
+			}
+			r.mind.values[state] = (float64(count)*r.mind.values[state] + gain) / float64(count+1)
+		} else {
+			// update V by correction to the new value with learning rate
+			oldValue, ok := r.mind.values[state]
+			if !ok {
+				oldValue = r.mind.specs.explore.unknownValue(r.rng)
+			}
+			r.mind.values[state] = oldValue + alp*(gain-oldValue)
+		}
+	}
+	return
+}
+
+// should be run after each new state is appended to the history
+// The newest state's value (or the final reward, if the episode is over) is the target of
+// the previous state; the error is passed on to earlier states by eligibility traces that
+// decay by gam*lam per step.
+func (r *robot) updateStateValuesTD(env environment, symbol string) {
+	n := len(r.history) - 1
+	if n < 1 {
+		return
+	}
+	target := r.shaping[n]
+	if env.gameOver {
+		target += r.mind.specs.rewards.reward(env.winner, symbol)
+	} else {
+		target += r.mind.specs.gam * r.stateValue(r.history[n])
+	}
+	delta := target - r.stateValue(r.history[n-1])
+	trace := 1.0
+	for i := n - 1; i >= 0 && trace > 0; i-- {
+		r.correctStateValue(r.history[i], trace*delta)
+		trace *= r.mind.specs.gam * r.mind.specs.lam
+	}
+	if env.gameOver {
+		// the final state has no future reward
+		r.mind.values[r.history[n]] = 0
+	}
+	return
+}
+
+// value of a state; an unknown state takes the default value, or an optimistic one
+func (r *robot) stateValue(state stateKey) float64 {
+	value, ok := r.mind.values[state]
+	if !ok {
+		value = r.mind.specs.explore.unknownValue(r.rng)
+	}
+	return value
+}
+
+// correct the value of a state by the error with learning rate, or with the inverse of the
+// times it has appeared if alp is zero throughout
+func (r *robot) correctStateValue(state stateKey, delta float64) {
+	rate := r.alp()
+	if r.mind.specs.alp.alwaysZero() {
+		rate = 1.0 / float64(r.mind.counts[state]+1)
+	}
+	r.mind.values[state] = r.stateValue(state) + rate*delta
+	return
+}
+
+// current learning rate, following the schedule of the robot
+func (r *robot) alp() float64 {
+	return r.mind.specs.alp.at(r.mind.episodes)
+}
+
+// current probability of a random action, following the schedule of the robot
+func (r *robot) eps() float64 {
+	return r.mind.specs.eps.at(r.mind.episodes)
+}
+
+// generate a value of certain mean and certain randomness
+func defaultValue(rng *rand.Rand) float64 {
+	return initialValue + fluctuation*(rng.Float64()-0.5)
+}
+
+// should be run right after updateStateValues()
+func (r *robot) updateStateValueHistory(env environment) {
+	for state := range r.mind.demohist {
+		r.mind.demohist[state] = append(r.mind.demohist[state], r.mind.values[state])
+	}
+	return
+}
+
+// update the record of how many times each state has appeared
+func (r *robot) updateStateCounts() {
+	for _, state := range r.history {
+		count, ok := r.mind.counts[state]
+		if !ok { // this state appears the first time
+			count = 0
+		}
+		r.mind.counts[state] = count + 1
+	}
+	return
+}
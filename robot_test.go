@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+// play the moves on a 3x3 board, letting the robot playing x observe each and learn at the end
+func playRobot(r *robot, moves []location) environment {
+	var env environment
+	env.initializeEnvironment(defaultSpec)
+	r.startSession(false)
+	symbol := "x"
+	for _, loc := range moves {
+		env.updateGameStatus(loc, symbol)
+		r.observe(env, "x")
+		symbol = opponentSymbol(symbol)
+	}
+	r.learn(env, "x")
+	return env
+}
+
+// the final state of a TD episode has no future reward, so its value is exactly zero
+func TestTDFinalStateValue(t *testing.T) {
+	r := newRobot("r", robotSpecs{algo: temporalDifference, alp: constantSchedule(0.1), gam: 0.9, rewards: defaultRewards})
+	// x wins along the top row
+	moves := []location{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}
+	var env environment
+	for i := 0; i < 3; i++ {
+		env = playRobot(r, moves)
+	}
+	final := r.encodeState(&env.board, "x")
+	if v, ok := r.mind.values[final]; !ok || v != 0 {
+		t.Errorf("value of the final state %v is %v (known %v), want 0", final, v, ok)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// forms of schedules
+const (
+	constant     = "const"   // the value never changes
+	linearDecay  = "linear"  // linear:start:end:steps, from start to end over steps episodes
+	expDecay     = "exp"     // exp:start:rate, start*rate^n
+	inverseDecay = "inverse" // inverse:start:rate, start/(1+rate*n)
+	stepDecay    = "step"    // step:start:rate:steps, start multiplied by rate every steps episodes
+)
+
+// schedule of a spec over the number of episodes a robot has learnt from
+type schedule struct {
+	form  string  // form of the schedule, constant if empty
+	start float64 // value before the first episode; the value of a constant schedule
+	end   float64 // final value of linearDecay
+	rate  float64 // decay of expDecay, inverseDecay and stepDecay
+	steps int     // number of episodes to reach the end of linearDecay, or of each step of stepDecay
+}
+
+func constantSchedule(value float64) schedule {
+	return schedule{form: constant, start: value}
+}
+
+// value of the schedule after n episodes
+func (s schedule) at(n int) float64 {
+	switch s.form {
+	case linearDecay:
+		if n >= s.steps {
+			return s.end
+		}
+		return s.start + (s.end-s.start)*float64(n)/float64(s.steps)
+	case expDecay:
+		return s.start * math.Pow(s.rate, float64(n))
+	case inverseDecay:
+		return s.start / (1 + s.rate*float64(n))
+	case stepDecay:
+		return s.start * math.Pow(s.rate, float64(n/s.steps))
+	}
+	return s.start
+}
+
+// check whether the schedule is zero from the start and stays zero. A learning rate of zero
+// asks for running averages, but only if it's zero throughout: a rate decaying to zero just
+// stops the learning, rather than switching the update rule partway through.
+func (s schedule) alwaysZero() bool {
+	return s.start == 0 && (s.form != linearDecay || s.end == 0)
+}
+
+// check that the schedule stays in [0, 1]
+func (s schedule) validate() error {
+	if s.start < 0 || s.start > 1 || s.end < 0 || s.end > 1 {
+		return fmt.Errorf("schedule %v is out of [0, 1]", s)
+	}
+	switch s.form {
+	case linearDecay, stepDecay:
+		if s.steps < 1 {
+			return fmt.Errorf("schedule %v needs at least 1 episode per step", s)
+		}
+	}
+	switch s.form {
+	case expDecay, stepDecay:
+		if s.rate <= 0 || s.rate > 1 {
+			return fmt.Errorf("schedule %v needs a rate in (0, 1]", s)
+		}
+	case inverseDecay:
+		if s.rate < 0 {
+			return fmt.Errorf("schedule %v needs a non-negative rate", s)
+		}
+	}
+	return nil
+}
+
+// text form of the schedule, a plain number if constant, e.g. "0.1" or "exp:0.3:0.999"
+func (s schedule) String() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	switch s.form {
+	case linearDecay:
+		return strings.Join([]string{s.form, f(s.start), f(s.end), strconv.Itoa(s.steps)}, ":")
+	case expDecay, inverseDecay:
+		return strings.Join([]string{s.form, f(s.start), f(s.rate)}, ":")
+	case stepDecay:
+		return strings.Join([]string{s.form, f(s.start), f(s.rate), strconv.Itoa(s.steps)}, ":")
+	}
+	return f(s.start)
+}
+
+// parse the text form of a schedule; the schedule is also validated
+func parseSchedule(text string) (schedule, error) {
+	fields := strings.Split(text, ":")
+	var s schedule
+	var want int // number of fields after the form
+	switch s.form = fields[0]; s.form {
+	case linearDecay, stepDecay:
+		want = 3
+		if len(fields) == want+1 {
+			// the number of episodes is the last field, and a whole number
+			steps, err := strconv.Atoi(fields[want])
+			if err != nil {
+				return s, fmt.Errorf("schedule %q needs a whole number of episodes, got %q", text, fields[want])
+			}
+			s.steps = steps
+		}
+	case expDecay, inverseDecay:
+		want = 2
+	default:
+		if len(fields) != 1 {
+			return s, fmt.Errorf("schedule %q has unknown form %q", text, fields[0])
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return s, fmt.Errorf("invalid schedule %q", text)
+		}
+		s = constantSchedule(v)
+		return s, s.validate()
+	}
+	if len(fields) != want+1 {
+		return s, fmt.Errorf("schedule %q needs %v numbers after %q", text, want, s.form)
+	}
+	var nums []float64
+	for _, field := range fields[1:] {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return s, fmt.Errorf("invalid schedule %q", text)
+		}
+		nums = append(nums, v)
+	}
+	s.start = nums[0]
+	switch s.form {
+	case linearDecay:
+		s.end = nums[1]
+	case expDecay, inverseDecay, stepDecay:
+		s.rate = nums[1]
+	}
+	return s, s.validate()
+}
+
+// a schedule is read from a JSON number for a constant, or from its text form
+func (s *schedule) UnmarshalJSON(d []byte) error {
+	var v float64
+	if err := json.Unmarshal(d, &v); err == nil {
+		*s = constantSchedule(v)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(d, &text); err != nil {
+		return fmt.Errorf("a schedule is a number or a string, got %s", d)
+	}
+	parsed, err := parseSchedule(text)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestParseSchedule(t *testing.T) {
+	valid := map[string]schedule{
+		"0.1":              constantSchedule(0.1),
+		"linear:0.5:0:100": {form: linearDecay, start: 0.5, end: 0, steps: 100},
+		"exp:0.3:0.999":    {form: expDecay, start: 0.3, rate: 0.999},
+		"step:0.5:0.5:10":  {form: stepDecay, start: 0.5, rate: 0.5, steps: 10},
+	}
+	for text, want := range valid {
+		s, err := parseSchedule(text)
+		if err != nil || s != want {
+			t.Errorf("parseSchedule(%q) = %+v, %v; want %+v", text, s, err, want)
+		}
+		if s.String() != text {
+			t.Errorf("schedule %q is written %q", text, s.String())
+		}
+	}
+	// steps are a whole number of episodes, not truncated
+	for _, text := range []string{"linear:0.5:0:1.9", "step:0.5:0.5:2.5", "linear:0.5:0", "exp:0.3", "exp:2:0.9", "step:0.5:0.5:0", "cosine:0.5", "x"} {
+		if s, err := parseSchedule(text); err == nil {
+			t.Errorf("parseSchedule(%q) = %+v", text, s)
+		}
+	}
+}
+
+// only a learning rate of zero throughout asks for running averages
+func TestAlwaysZero(t *testing.T) {
+	if !constantSchedule(0).alwaysZero() || constantSchedule(0.1).alwaysZero() {
+		t.Errorf("constant schedules")
+	}
+	if (schedule{form: linearDecay, start: 0.5, end: 0, steps: 10}).alwaysZero() {
+		t.Errorf("a learning rate decaying to zero is zero throughout")
+	}
+}
+
+// once a learning rate decays to zero, the values stop changing, rather than being averaged
+func TestDecayToZeroStopsLearning(t *testing.T) {
+	r := newRobot("r", robotSpecs{alp: schedule{form: linearDecay, start: 0.5, end: 0, steps: 1}, gam: 0.9, rewards: defaultRewards})
+	moves := []location{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {0, 2}}
+	playRobot(r, moves)
+	learnt := stateValues{}
+	for state, v := range r.mind.values {
+		learnt[state] = v
+	}
+	playRobot(r, moves)
+	for state, v := range r.mind.values {
+		if v != learnt[state] {
+			t.Errorf("value of %v changed from %v to %v at a learning rate of zero", state, learnt[state], v)
+		}
+	}
+}
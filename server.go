@@ -0,0 +1,414 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const webIdleTimeout = 10 * time.Minute // a browser game without a move for this long is abandoned, and a finished one is removed
+const webMoveTimeout = 5 * time.Second  // time a posted move waits for the game to take it
+const maxWebBoard = 15                  // largest number of rows or columns of a browser game
+
+// server hosts games between browser users and the players, and the API managing players
+// and sessions. A player plays one game or session at a time, so a robot is never trained
+// by two of them at once.
+type server struct {
+	mu            sync.Mutex
+	players       []*player           // players created at start-up or through the API
+	busy          map[*player]bool    // players in a game or a session
+	games         map[int]*webGame    // games by id
+	nextID        int                 // id of the next game
+	sessions      map[int]*apiSession // sessions started through the API, by id
+	nextSessionID int                 // id of the next session
+	ratings       *ratingBook         // ratings updated by the games and sessions; not rated if nil
+	log           *gameLog            // log the episodes are recorded into; not recorded if nil
+}
+
+// webGame is a game between a browser user and a player; the state is updated by the
+// browser user's agent as the episode goes on
+type webGame struct {
+	mu        sync.Mutex
+	id        int
+	user      *player   // the browser user
+	opponent  *player   // the player the user plays against
+	spec      boardSpec // board of the game
+	board     board     // board after the latest move
+	symbol    string    // the user's symbol, once the episode has started
+	yourTurn  bool      // the user is to move
+	lastMove  *location // latest move, nil before the first one
+	over      bool      // the episode is over
+	abandoned bool      // the user has left the game, which ended without a result
+	winner    string    // winner's symbol, empty for a draw
+	err       error     // failure that ended the session, if any
+	moves     chan webMove
+	done      chan struct{} // closed when the session has ended
+	ended     time.Time     // time the session ended
+	touched   time.Time     // time of the latest request of the user
+}
+
+// webMove is a move posted by the user, answered with nil if the game takes it
+type webMove struct {
+	loc   location
+	reply chan error
+}
+
+// remote is the agent of a browser user: it shows the board through the game and waits for
+// the user's move
+type remote struct {
+	game      *webGame
+	abandoned bool // the user has left the game, which is abandoned
+}
+
+func (rm *remote) kind() string {
+	return "human"
+}
+
+func (rm *remote) startSession(verb bool) {
+	return
+}
+
+// the user has abandoned the game, which is neither learnt from, rated nor recorded
+func (rm *remote) quit() bool {
+	return rm.abandoned
+}
+
+// wait for a move posted by the user that is valid on the board; a user idle for too long
+// abandons the game, and no move is chosen
+func (rm *remote) act(env environment, symbol string) location {
+	g := rm.game
+	g.mu.Lock()
+	g.symbol, g.yourTurn = symbol, true
+	g.mu.Unlock()
+	for {
+		g.mu.Lock()
+		idle := time.Since(g.touched) > webIdleTimeout
+		if idle {
+			g.yourTurn, g.abandoned = false, true
+		}
+		g.mu.Unlock()
+		if idle {
+			rm.abandoned = true
+			return location{}
+		}
+		select {
+		case m := <-g.moves:
+			loc := m.loc
+			if loc[0] < 0 || loc[0] >= env.spec.rows || loc[1] < 0 || loc[1] >= env.spec.cols || env.board[loc[0]][loc[1]] != "" {
+				m.reply <- fmt.Errorf("%v %v is not an empty location of the board", loc[0], loc[1])
+				continue
+			}
+			g.mu.Lock()
+			g.yourTurn = false
+			g.mu.Unlock()
+			m.reply <- nil
+			return loc
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// show the board following each move to the user
+func (rm *remote) observe(env environment, symbol string) {
+	g := rm.game
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.symbol = symbol
+	for irow := range env.board {
+		copy(g.board[irow], env.board[irow])
+	}
+	loc := env.lastMove
+	g.lastMove = &loc
+	g.over, g.winner = env.gameOver, env.winner
+	return
+}
+
+func (rm *remote) learn(env environment, symbol string) {
+	return
+}
+
+func (rm *remote) export(p *player) error {
+	return nil
+}
+
+// serve the web page and the API on the address until the server fails
+func serve(addr string, players []player, ratings *ratingBook, games *gameLog) error {
+	s := &server{busy: map[*player]bool{}, games: map[int]*webGame{}, sessions: map[int]*apiSession{}, ratings: ratings, log: games}
+	for i := range players {
+		s.players = append(s.players, &players[i])
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/opponents", s.handleOpponents)
+	mux.HandleFunc("/api/games", s.handleGames)
+	mux.HandleFunc("/api/games/", s.handleGame)
+	mux.HandleFunc("/api/players", s.handlePlayers)
+	mux.HandleFunc("/api/players/", s.handlePlayer)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/sessions/", s.handleSession)
+	fmt.Printf("*** Serving on %v *** \n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (s *server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, indexPage)
+}
+
+// playerInfo describes a player
+type playerInfo struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Busy bool   `json:"busy"`
+}
+
+// GET /api/opponents lists the players a browser user can play against
+func (s *server) handleOpponents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := []playerInfo{}
+	for _, p := range s.players {
+		if p.agent.kind() != "human" {
+			infos = append(infos, s.playerInfo(p))
+		}
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// newGameRequest is the body of a request to start a game
+type newGameRequest struct {
+	Name     string       `json:"name"`     // name of the user; "guest" if empty
+	Opponent string       `json:"opponent"` // name of the opponent
+	Board    *boardConfig `json:"board"`    // default board if omitted
+	First    string       `json:"first"`    // who plays first, "user", "opponent" or "random" (default)
+}
+
+// POST /api/games starts a game against an opponent
+func (s *server) handleGames(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+	var req newGameRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	spec := sessionConfig{Board: req.Board}.spec()
+	err := spec.validate()
+	if err == nil && (spec.rows > maxWebBoard || spec.cols > maxWebBoard) {
+		err = fmt.Errorf("a browser game is played on at most %vx%v", maxWebBoard, maxWebBoard)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	first := randomFirst
+	switch req.First {
+	case "user":
+		first = 0
+	case "opponent":
+		first = 1
+	case "", "random":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown first player %q", req.First))
+		return
+	}
+	if req.Name == "" {
+		req.Name = "guest"
+	}
+
+	// take the opponent
+	s.mu.Lock()
+	s.removeEndedGames()
+	opponent := s.findPlayer(req.Opponent)
+	if opponent == nil || opponent.agent.kind() == "human" {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, fmt.Errorf("no opponent %q", req.Opponent))
+		return
+	}
+	if s.busy[opponent] {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("%v is playing another game", opponent.name))
+		return
+	}
+	s.busy[opponent] = true
+	g := &webGame{id: s.nextID, opponent: opponent, spec: spec, moves: make(chan webMove), done: make(chan struct{}), touched: time.Now()}
+	s.nextID++
+	s.games[g.id] = g
+	s.mu.Unlock()
+
+	var env environment
+	env.initializeEnvironment(spec)
+	g.board = env.board
+	g.user = &player{name: req.Name, agent: &remote{game: g}}
+	opts := sessionOptions{episodes: 1, spec: spec, first: first, ratings: s.ratings, rng: newRand(), games: s.log}
+	go func() {
+		_, err := runSession(&playerPair{g.user, opponent}, opts)
+		g.mu.Lock()
+		g.over, g.yourTurn, g.err, g.ended = true, false, err, time.Now()
+		g.mu.Unlock()
+		close(g.done)
+		s.mu.Lock()
+		s.busy[opponent] = false
+		s.mu.Unlock()
+	}()
+	writeJSON(w, http.StatusCreated, map[string]int{"id": g.id})
+}
+
+// remove the games ended long enough ago for the users to have seen the result; s.mu must be
+// held. An abandoned game ends by itself once its user is idle.
+func (s *server) removeEndedGames() {
+	for id, g := range s.games {
+		g.mu.Lock()
+		remove := !g.ended.IsZero() && time.Since(g.ended) > webIdleTimeout
+		g.mu.Unlock()
+		if remove {
+			delete(s.games, id)
+		}
+	}
+	return
+}
+
+// gameState is the state of a game shown to the browser user
+type gameState struct {
+	ID        int       `json:"id"`
+	Opponent  string    `json:"opponent"`
+	Board     board     `json:"board"`
+	K         int       `json:"k"`
+	Symbol    string    `json:"symbol"`    // the user's symbol, empty before the episode starts
+	YourTurn  bool      `json:"your_turn"` // the user is to move
+	LastMove  *location `json:"last_move"` // null before the first move
+	Over      bool      `json:"over"`
+	Abandoned bool      `json:"abandoned"`       // the game ended without a result, as the user was idle
+	Winner    string    `json:"winner"`          // winner's symbol, empty for a draw or a game not over
+	Error     string    `json:"error,omitempty"` // failure that ended the game early
+}
+
+// GET /api/games/{id} returns the state of the game; POST /api/games/{id}/moves with
+// {"row": r, "col": c} makes the user's move
+func (s *server) handleGame(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/games/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.Lock()
+	g, ok := s.games[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no game %v", id))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		g.mu.Lock()
+		g.touched = time.Now()
+		gs := gameState{
+			ID: g.id, Opponent: g.opponent.name, Board: g.board, K: g.spec.k, Symbol: g.symbol,
+			YourTurn: g.yourTurn, LastMove: g.lastMove, Over: g.over, Abandoned: g.abandoned, Winner: g.winner,
+		}
+		if g.err != nil {
+			gs.Error = g.err.Error()
+		}
+		d, err := json.Marshal(gs) // marshal under the lock, as the board is shared
+		g.mu.Unlock()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(d)
+	case len(parts) == 2 && parts[1] == "moves" && r.Method == http.MethodPost:
+		var move struct {
+			Row int `json:"row"`
+			Col int `json:"col"`
+		}
+		if err := readJSON(r, &move); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		g.mu.Lock()
+		g.touched = time.Now()
+		var err error
+		if !g.yourTurn || g.over {
+			err = fmt.Errorf("it's not your turn")
+		} else if move.Row < 0 || move.Row >= g.spec.rows || move.Col < 0 || move.Col >= g.spec.cols {
+			err = fmt.Errorf("location %v %v is off the %v board", move.Row, move.Col, g.spec)
+		} else if g.board[move.Row][move.Col] != "" {
+			err = fmt.Errorf("location %v %v is taken", move.Row, move.Col)
+		}
+		g.mu.Unlock()
+		if err == nil {
+			// the agent checks the move again on its board, which is the final word
+			m := webMove{location{move.Row, move.Col}, make(chan error, 1)}
+			select {
+			case g.moves <- m:
+				err = <-m.reply
+			case <-g.done:
+				err = fmt.Errorf("the game is over")
+			case <-time.After(webMoveTimeout):
+				err = fmt.Errorf("it's not your turn")
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%v %v not allowed", r.Method, r.URL.Path))
+	}
+}
+
+// describe the player; s.mu must be held
+func (s *server) playerInfo(p *player) playerInfo {
+	return playerInfo{Name: p.name, Kind: p.agent.kind(), Busy: s.busy[p]}
+}
+
+// find the player by name; s.mu must be held
+func (s *server) findPlayer(name string) *player {
+	for _, p := range s.players {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// decode the JSON body of the request, refusing unknown fields
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print("Cannot write response ", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
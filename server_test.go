@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// a new browser game on an empty board, touched just now
+func newTestGame() *webGame {
+	var env environment
+	env.initializeEnvironment(defaultSpec)
+	return &webGame{spec: defaultSpec, board: env.board, moves: make(chan webMove), touched: time.Now()}
+}
+
+// a move to an occupied location is refused, and the user can move again
+func TestRemoteActMoves(t *testing.T) {
+	g := newTestGame()
+	rm := &remote{game: g}
+	var env environment
+	env.initializeEnvironment(defaultSpec)
+	env.updateGameStatus(location{1, 1}, "x")
+	done := make(chan location)
+	go func() { done <- rm.act(env, "o") }()
+	for _, m := range []struct {
+		loc location
+		ok  bool
+	}{{location{1, 1}, false}, {location{3, 0}, false}, {location{0, 2}, true}} {
+		reply := make(chan error, 1)
+		g.moves <- webMove{m.loc, reply}
+		if err := <-reply; (err == nil) != m.ok {
+			t.Errorf("move %v: got error %v, want ok %v", m.loc, err, m.ok)
+		}
+	}
+	if loc := <-done; loc != (location{0, 2}) {
+		t.Errorf("got move %v, want [0 2]", loc)
+	}
+}
+
+// games ended long enough ago are removed, the others are kept
+func TestRemoveEndedGames(t *testing.T) {
+	old, recent, running := newTestGame(), newTestGame(), newTestGame()
+	old.ended = time.Now().Add(-webIdleTimeout - time.Second)
+	recent.ended = time.Now()
+	s := &server{games: map[int]*webGame{1: old, 2: recent, 3: running}}
+	s.removeEndedGames()
+	if _, ok := s.games[1]; ok || len(s.games) != 2 {
+		t.Errorf("games left: %v, want 2 and 3", s.games)
+	}
+}
+
+// an idle user abandons the game instead of moving, which quits the session
+func TestRemoteActIdle(t *testing.T) {
+	g := newTestGame()
+	g.touched = time.Now().Add(-webIdleTimeout - time.Second)
+	rm := &remote{game: g}
+	var env environment
+	env.initializeEnvironment(defaultSpec)
+	rm.act(env, "x")
+	if !rm.quit() || !g.abandoned || g.yourTurn {
+		t.Errorf("quit %v, abandoned %v and your turn %v, want the game abandoned", rm.quit(), g.abandoned, g.yourTurn)
+	}
+}
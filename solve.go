@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// opponent models of the solver
+const (
+	optimalOpponent = "optimal" // the opponent always makes the move worst for the robot
+	randomOpponent  = "random"  // the opponent moves uniformly at random
+	epsOpponent     = "eps"     // eps:E, the opponent moves at random with probability E, otherwise optimally
+)
+
+// solver computes the exact value of every reachable state, in the perspective of a robot
+// that plays greedily on those values against an opponent model, with the same rewards and
+// encoding as the robots: a state is worth the expected gain of the next move, where a move
+// ending the episode gains its reward, any other move gam times the value of the state it
+// leads to, and a final state is worth zero. As every move adds a symbol to the board, the
+// states form a DAG, and one sweep from the final states backward, done here by recursion
+// with memoization, is all the value iteration needed.
+type solver struct {
+	spec     boardSpec
+	opponent string      // opponent model
+	eps      float64     // probability of a random move of an epsOpponent
+	gam      float64     // discount factor
+	sym      bool        // solve on canonical states
+	values   stateValues // solved values, in the perspective of the robot
+}
+
+// parse the opponent model, e.g. "optimal", "random" or "eps:0.1"
+func parseOpponent(text string) (string, float64, error) {
+	fields := strings.SplitN(text, ":", 2)
+	switch fields[0] {
+	case optimalOpponent, randomOpponent:
+		if len(fields) == 1 {
+			return fields[0], 0, nil
+		}
+	case epsOpponent:
+		if len(fields) == 2 {
+			eps, err := strconv.ParseFloat(fields[1], 64)
+			if err == nil && eps >= 0 && eps <= 1 {
+				return fields[0], eps, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("invalid opponent model %q, expected %v, %v or %v:E", text, optimalOpponent, randomOpponent, epsOpponent)
+}
+
+// solve the values of every state reachable on the board, for a robot playing either symbol,
+// and export them like the values of a robot
+func solveValues(spec boardSpec, opponent string, gam float64, sym bool) error {
+	model, eps, err := parseOpponent(opponent)
+	if err != nil {
+		return err
+	}
+	if gam < 0 || gam > 1 {
+		return fmt.Errorf("gam %v is out of [0, 1]", gam)
+	}
+	sv := solver{spec: spec, opponent: model, eps: eps, gam: gam, sym: sym, values: stateValues{}}
+	var env environment
+	env.initializeEnvironment(spec)
+	for _, symbol := range []string{"x", "o"} {
+		sv.value(env.board, symbol)
+	}
+	fmt.Printf("*** Values solved on a %v board against the %v opponent *** \n", spec, opponent)
+	exportValues("solved_"+strings.Replace(opponent, ":", "_", 1), sv.values, sym)
+	return nil
+}
+
+// value of the non-final board in the perspective of the robot playing symbol
+func (sv *solver) value(b board, symbol string) float64 {
+	state := boardToState(&b, symbol)
+	if sv.sym {
+		state = state.canonical()
+	}
+	if value, ok := sv.values[state]; ok {
+		return value
+	}
+
+	// gain of each move of the player to move
+	mover := nextSymbol(b)
+	empties := getEmpties(b)
+	var gains []float64
+	for irow, row := range b {
+		for icol, element := range row {
+			if element != "" {
+				continue
+			}
+			b[irow][icol] = mover
+			if isWinningMove(b, location{irow, icol}, sv.spec.k) {
+				sv.final(b, symbol)
+				gains = append(gains, getReward(mover, symbol))
+			} else if empties == 1 {
+				sv.final(b, symbol)
+				gains = append(gains, getReward("", symbol))
+			} else {
+				gains = append(gains, sv.gam*sv.value(b, symbol))
+			}
+			b[irow][icol] = ""
+		}
+	}
+
+	// the robot takes the best move; the opponent moves by its model
+	max, min, mean := math.Inf(-1), math.Inf(1), 0.0
+	for _, gain := range gains {
+		max = math.Max(max, gain)
+		min = math.Min(min, gain)
+		mean += gain / float64(len(gains))
+	}
+	var value float64
+	switch {
+	case mover == symbol:
+		value = max
+	case sv.opponent == optimalOpponent:
+		value = min
+	case sv.opponent == randomOpponent:
+		value = mean
+	default:
+		value = sv.eps*mean + (1-sv.eps)*min
+	}
+	sv.values[state] = value
+	return value
+}
+
+// record the final board, which is worth zero
+func (sv *solver) final(b board, symbol string) {
+	state := boardToState(&b, symbol)
+	if sv.sym {
+		state = state.canonical()
+	}
+	sv.values[state] = 0
+	return
+}
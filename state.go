@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stateKey identifies a board in a player's perspective, for a board of any size.
+// It is a compact byte string:
+//   - byte 0 and byte 1 are the number of rows and columns of the board
+//   - byte 2 is the player's symbol, 0 for "x" and 1 for "o"
+//   - the remaining bytes pack the locations row by row, 4 locations per byte and 2 bits
+//     per location: 0 occupied by the player, 1 empty, 2 occupied by the opponent
+//
+// Its textual encoding (see String) is used in the exported files.
+type stateKey string
+
+// number of header bytes before the packed locations
+const stateHeader = 3
+
+// encode the game board into a state key
+// NOTE: For each player, each location's status is viewed only as occupied either by
+// him/herself or by the opponent, regardless of the actual symbol ("x" or "o") there.
+// NOTE: For the same board and the same user, the player plays next or the opponent plays
+// next makes different states.
+func boardToState(b *board, symbol string) stateKey {
+	nrows, ncols := len(*b), 0
+	if nrows > 0 {
+		ncols = len((*b)[0])
+	}
+	h := make([]byte, stateHeader+(nrows*ncols+3)/4)
+	h[0], h[1] = byte(nrows), byte(ncols)
+	// encode player symbol
+	if symbol != "x" {
+		h[2] = 1
+	}
+	// encode board
+	var k int
+	for _, row := range *b {
+		for _, element := range row {
+			var v byte
+			if element == symbol { // occupied by current player
+				v = 0
+			} else if element == "" { // empty
+				v = 1
+			} else { // occupied by opponent
+				v = 2
+			}
+			h[stateHeader+k/4] |= v << uint(2*(k%4))
+			k++
+		}
+	}
+	return stateKey(h)
+}
+
+// size of the board encoded in the state key
+func (h stateKey) size() (int, int) {
+	return int(h[0]), int(h[1])
+}
+
+// symbol of the player whose perspective the state key is in
+func (h stateKey) symbol() string {
+	if h[2] == 0 {
+		return "x"
+	}
+	return "o"
+}
+
+// status of the k-th location (row by row) encoded in the state key
+func (h stateKey) cell(k int) byte {
+	return (h[stateHeader+k/4] >> uint(2*(k%4))) & 3
+}
+
+// decode the state key to reconstruct the board in player's perspective
+func stateToBoard(h stateKey) (board, string) {
+	nrows, ncols := h.size()
+	b := make(board, nrows)
+	var k int
+	for irow := range b {
+		r := make([]string, ncols)
+		for ielement := range r {
+			v := h.cell(k)
+			if v == 0 {
+				r[ielement] = "P" // the player
+			} else if v == 1 {
+				r[ielement] = "" // empty
+			} else {
+				r[ielement] = "-" // the opponent
+			}
+			k++
+		}
+		b[irow] = r
+	}
+	return b, h.symbol()
+}
+
+// String returns the textual encoding of the state key, "<rows>x<cols>:<symbol>:<locations>",
+// where locations are digits row by row: 0 the player, 1 empty, 2 the opponent.
+// For example, "3x3:o:111101111" is the 3x3 board with only the opponent ("x") in the center.
+func (h stateKey) String() string {
+	nrows, ncols := h.size()
+	digits := make([]byte, nrows*ncols)
+	for k := range digits {
+		digits[k] = '0' + h.cell(k)
+	}
+	return fmt.Sprintf("%vx%v:%v:%s", nrows, ncols, h.symbol(), digits)
+}
+
+// parse the textual encoding of a state key
+func parseStateKey(s string) (stateKey, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid state %q", s)
+	}
+	size := strings.Split(parts[0], "x")
+	if len(size) != 2 {
+		return "", fmt.Errorf("invalid board size in state %q", s)
+	}
+	nrows, err1 := strconv.Atoi(size[0])
+	ncols, err2 := strconv.Atoi(size[1])
+	if err1 != nil || err2 != nil || nrows < 1 || ncols < 1 || nrows > 255 || ncols > 255 {
+		return "", fmt.Errorf("invalid board size in state %q", s)
+	}
+	if parts[1] != "x" && parts[1] != "o" {
+		return "", fmt.Errorf("invalid symbol in state %q", s)
+	}
+	if len(parts[2]) != nrows*ncols {
+		return "", fmt.Errorf("invalid number of locations in state %q", s)
+	}
+	h := make([]byte, stateHeader+(nrows*ncols+3)/4)
+	h[0], h[1] = byte(nrows), byte(ncols)
+	if parts[1] == "o" {
+		h[2] = 1
+	}
+	for k, c := range []byte(parts[2]) {
+		if c < '0' || c > '2' {
+			return "", fmt.Errorf("invalid location %q in state %q", c, s)
+		}
+		h[stateHeader+k/4] |= (c - '0') << uint(2*(k%4))
+	}
+	return stateKey(h), nil
+}
+
+// symmetries of a rows x cols board, each mapping a location to its transformed location:
+// the 8 rotations/reflections of a square board, or the 4 of a non-square board
+func symmetries(nrows, ncols int) []func(irow, icol int) (int, int) {
+	r, c := nrows-1, ncols-1
+	syms := []func(irow, icol int) (int, int){
+		func(irow, icol int) (int, int) { return irow, icol },         // identity
+		func(irow, icol int) (int, int) { return r - irow, c - icol }, // rotate 180
+		func(irow, icol int) (int, int) { return irow, c - icol },     // mirror left-right
+		func(irow, icol int) (int, int) { return r - irow, icol },     // mirror top-bottom
+	}
+	if nrows == ncols {
+		syms = append(syms,
+			func(irow, icol int) (int, int) { return icol, c - irow },     // rotate 90
+			func(irow, icol int) (int, int) { return c - icol, irow },     // rotate 270
+			func(irow, icol int) (int, int) { return icol, irow },         // main diagonal
+			func(irow, icol int) (int, int) { return c - icol, r - irow }, // anti-diagonal
+		)
+	}
+	return syms
+}
+
+// all the distinct state keys the board can be transformed into by its symmetries
+func (h stateKey) orbit() []stateKey {
+	nrows, ncols := h.size()
+	var keys []stateKey
+	seen := map[stateKey]bool{}
+	for _, sym := range symmetries(nrows, ncols) {
+		t := []byte(h)
+		for k := stateHeader; k < len(t); k++ {
+			t[k] = 0
+		}
+		for irow := 0; irow < nrows; irow++ {
+			for icol := 0; icol < ncols; icol++ {
+				trow, tcol := sym(irow, icol)
+				k := trow*ncols + tcol
+				t[stateHeader+k/4] |= h.cell(irow*ncols+icol) << uint(2*(k%4))
+			}
+		}
+		if key := stateKey(t); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// canonical representative of the state among the board's symmetries, which is the
+// smallest key of its orbit
+func (h stateKey) canonical() stateKey {
+	best := h
+	for _, key := range h.orbit() {
+		if key < best {
+			best = key
+		}
+	}
+	return best
+}
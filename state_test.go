@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// random board of the size, with about a third of the locations of each kind
+func randomBoard(rng *rand.Rand, nrows, ncols int) board {
+	b := make(board, nrows)
+	for irow := range b {
+		b[irow] = make([]string, ncols)
+		for icol := range b[irow] {
+			b[irow][icol] = []string{"", "x", "o"}[rng.Intn(3)]
+		}
+	}
+	return b
+}
+
+// a board is encoded into a state key and back, and the key into its text and back, for boards
+// of any size up to 255x255, square or not
+func TestStateKeyRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sizes := [][2]int{{1, 1}, {3, 3}, {3, 5}, {7, 2}, {4, 4}, {1, maxBoardSize}, {maxBoardSize, 1}, {maxBoardSize, 3}, {maxBoardSize, maxBoardSize}}
+	for _, size := range sizes {
+		for _, symbol := range []string{"x", "o"} {
+			b := randomBoard(rng, size[0], size[1])
+			h := boardToState(&b, symbol)
+			if nrows, ncols := h.size(); nrows != size[0] || ncols != size[1] {
+				t.Fatalf("%vx%v board encoded as %vx%v", size[0], size[1], nrows, ncols)
+			}
+			decoded, s := stateToBoard(h)
+			if s != symbol {
+				t.Errorf("%vx%v board of %v decoded for %v", size[0], size[1], symbol, s)
+			}
+			for irow, row := range b {
+				for icol, element := range row {
+					want := "-" // the opponent
+					if element == "" {
+						want = ""
+					} else if element == symbol {
+						want = "P"
+					}
+					if decoded[irow][icol] != want {
+						t.Fatalf("%vx%v board of %v: location %v %v is %q, decoded as %q", size[0], size[1], symbol, irow, icol, element, decoded[irow][icol])
+					}
+				}
+			}
+			parsed, err := parseStateKey(h.String())
+			if err != nil {
+				t.Fatalf("%vx%v board of %v: %v", size[0], size[1], symbol, err)
+			}
+			if parsed != h {
+				t.Errorf("%vx%v board of %v: parsed key %v, want %v", size[0], size[1], symbol, parsed, h)
+			}
+		}
+	}
+}
+
+// boards of the same number of locations but different sizes have different keys
+func TestStateKeySizes(t *testing.T) {
+	keys := map[stateKey][2]int{}
+	for _, size := range [][2]int{{1, 16}, {2, 8}, {4, 4}, {8, 2}, {16, 1}} {
+		b := make(board, size[0])
+		for irow := range b {
+			b[irow] = make([]string, size[1])
+		}
+		h := boardToState(&b, "x")
+		if other, ok := keys[h]; ok {
+			t.Errorf("%vx%v and %vx%v boards share the key %v", size[0], size[1], other[0], other[1], h)
+		}
+		keys[h] = size
+	}
+}
+
+func TestParseStateKeyErrors(t *testing.T) {
+	for _, s := range []string{"", "3x3:x", "3x3:x:11111111", "3x3:z:111111111", "3x3:x:111131111", "0x3:x:", "256x1:x:" + strings.Repeat("1", 256), "3:x:111"} {
+		if _, err := parseStateKey(s); err == nil {
+			t.Errorf("state %q is parsed", s)
+		}
+	}
+}
+
+// all orientations of a board share the canonical key, which is among them
+func TestCanonical(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, size := range [][2]int{{3, 3}, {4, 4}, {3, 5}} {
+		b := randomBoard(rng, size[0], size[1])
+		h := boardToState(&b, "x")
+		c := h.canonical()
+		found := false
+		for _, o := range h.orbit() {
+			if o.canonical() != c {
+				t.Errorf("%v and %v have different canonical keys", h, o)
+			}
+			found = found || o == c
+		}
+		if !found {
+			t.Errorf("canonical key %v of %v is not an orientation of it", c, h)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// tournament formats
+const (
+	roundRobin       = "rr"    // every pair of players plays a session
+	doubleRoundRobin = "drr"   // every pair plays two sessions, each player playing first in one of them
+	swiss            = "swiss" // each round pairs players of similar points who haven't met yet
+)
+
+type tournament struct {
+	format string         // roundRobin, doubleRoundRobin or swiss
+	rounds int            // number of rounds of a swiss tournament
+	opts   sessionOptions // options of each session; the first player is set by the format
+}
+
+// standing is a player's record in a tournament. A session is won by the player who wins
+// more of its episodes, and scores 1 point; a tied session scores half a point each, and a
+// bye in a swiss round scores 1 point.
+type standing struct {
+	player    *player
+	seed      int          // order in the list of players
+	sessions  [3]int       // sessions won, tied and lost
+	episodes  [3]int       // episodes won, drawn and lost
+	points    float64      // tournament points
+	byes      int          // rounds without an opponent
+	opponents map[int]bool // seeds of the players already met
+}
+
+// number of swiss rounds needed to find a single leader among n players
+func defaultSwissRounds(n int) int {
+	return int(math.Ceil(math.Log2(float64(n))))
+}
+
+// ask the user for the format of a tournament
+func promptTournamentFormat() string {
+	var format string
+	for {
+		fmt.Printf("tournament format (%v/%v/%v): ", roundRobin, doubleRoundRobin, swiss)
+		_, err := fmt.Scanf("%s", &format)
+		if err == nil && (format == roundRobin || format == doubleRoundRobin || format == swiss) {
+			return format
+		}
+	}
+}
+
+// schedule and run the sessions of a tournament between all the players, then print the
+// standings; return the failures of its sessions, whose episodes played still count
+func runTournament(players []*player, t tournament) error {
+	n := len(players)
+	fmt.Printf("*** Tournament starts: %v players, format %v *** \n\n", n, t.format)
+	standings := make([]*standing, n)
+	for i, p := range players {
+		standings[i] = &standing{player: p, seed: i, opponents: map[int]bool{}}
+	}
+	// headToHead[i][j] is the number of episodes won, drawn and lost by player i against player j
+	headToHead := make([][][3]int, n)
+	for i := range headToHead {
+		headToHead[i] = make([][3]int, n)
+	}
+
+	var errs []error
+	play := func(i, j, first int) {
+		opts := t.opts
+		opts.first = first
+		result, err := runSession(&playerPair{players[i], players[j]}, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v vs %v: %v", players[i].name, players[j].name, err))
+		}
+		si, sj := standings[i], standings[j]
+		si.opponents[j], sj.opponents[i] = true, true
+		for _, r := range [2][3]int{{i, j, 0}, {j, i, 1}} {
+			s, won, lost := standings[r[0]], result.wins[r[2]], result.wins[1-r[2]]
+			s.episodes[0] += won
+			s.episodes[1] += result.draws
+			s.episodes[2] += lost
+			headToHead[r[0]][r[1]][0] += won
+			headToHead[r[0]][r[1]][1] += result.draws
+			headToHead[r[0]][r[1]][2] += lost
+			if won > lost {
+				s.sessions[0]++
+				s.points++
+			} else if won == lost {
+				s.sessions[1]++
+				s.points += 0.5
+			} else {
+				s.sessions[2]++
+			}
+		}
+		return
+	}
+
+	switch t.format {
+	case roundRobin:
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				play(i, j, randomFirst)
+			}
+		}
+	case doubleRoundRobin:
+		for leg := 0; leg < 2; leg++ {
+			for i := 0; i < n; i++ {
+				for j := i + 1; j < n; j++ {
+					play(i, j, leg) // player i plays first in the first leg, player j in the second
+				}
+			}
+		}
+	case swiss:
+		for round := 1; round <= t.rounds; round++ {
+			fmt.Printf("*** Swiss round %v *** \n\n", round)
+			pairs, bye := swissPairs(standings)
+			if bye != nil {
+				bye.byes++
+				bye.points++
+				fmt.Printf("%v has a bye \n\n", bye.player.name)
+			}
+			for _, pair := range pairs {
+				play(pair[0], pair[1], randomFirst)
+			}
+		}
+	}
+
+	printStandings(standings, headToHead)
+	return errors.Join(errs...)
+}
+
+// order standings by points, then by episodes won minus lost, then by seed
+func rankStandings(standings []*standing) []*standing {
+	ranked := append([]*standing{}, standings...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.points != b.points {
+			return a.points > b.points
+		}
+		if da, db := a.episodes[0]-a.episodes[2], b.episodes[0]-b.episodes[2]; da != db {
+			return da > db
+		}
+		return a.seed < b.seed
+	})
+	return ranked
+}
+
+// pair players of a swiss round: going down the ranking, each player meets the next one
+// not met yet, if any. With an odd number of players, the lowest ranked among those with
+// the fewest byes sits out.
+func swissPairs(standings []*standing) ([][2]int, *standing) {
+	ranked := rankStandings(standings)
+	var bye *standing
+	if len(ranked)%2 == 1 {
+		ib := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if ranked[i].byes < ranked[ib].byes {
+				ib = i
+			}
+		}
+		bye = ranked[ib]
+		ranked = append(ranked[:ib:ib], ranked[ib+1:]...)
+	}
+	var pairs [][2]int
+	for len(ranked) > 0 {
+		a := ranked[0]
+		ib := 1
+		for i := 1; i < len(ranked); i++ {
+			if !a.opponents[ranked[i].seed] {
+				ib = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]int{a.seed, ranked[ib].seed})
+		ranked = append(ranked[1:ib:ib], ranked[ib+1:]...)
+	}
+	return pairs, bye
+}
+
+// print the final standings and the head-to-head records
+func printStandings(standings []*standing, headToHead [][][3]int) {
+	ranked := rankStandings(standings)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Print("*** Tournament standings *** \n")
+	fmt.Fprintln(w, "rank\tplayer\tpoints\tsessions W/D/L\tepisodes W/D/L\t")
+	for i, s := range ranked {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v/%v/%v\t%v/%v/%v\t\n", i+1, s.player.name, s.points,
+			s.sessions[0], s.sessions[1], s.sessions[2], s.episodes[0], s.episodes[1], s.episodes[2])
+	}
+	w.Flush()
+
+	fmt.Print("\n*** Head-to-head episodes W/D/L (row against column) *** \n")
+	fmt.Fprint(w, "\t")
+	for _, s := range ranked {
+		fmt.Fprintf(w, "%v\t", s.player.name)
+	}
+	fmt.Fprintln(w)
+	for _, a := range ranked {
+		fmt.Fprintf(w, "%v\t", a.player.name)
+		for _, b := range ranked {
+			if a == b || !a.opponents[b.seed] {
+				fmt.Fprint(w, "-\t")
+				continue
+			}
+			r := headToHead[a.seed][b.seed]
+			fmt.Fprintf(w, "%v/%v/%v\t", r[0], r[1], r[2])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	fmt.Println()
+	return
+}
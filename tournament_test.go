@@ -0,0 +1,23 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// a tournament returns the failures of its sessions, and its other sessions still play
+func TestTournamentFailures(t *testing.T) {
+	players := []*player{{name: "a", agent: &stubAgent{}}, {name: "b", agent: &stubAgent{}}, {name: "c", agent: &failingExport{}}}
+	opts := sessionOptions{episodes: 2, spec: defaultSpec, first: randomFirst, rng: rand.New(rand.NewSource(1))}
+	err := runTournament(players, tournament{format: roundRobin, opts: opts})
+	if err == nil {
+		t.Fatal("got no error, want the failures of the sessions of c")
+	}
+	if n := strings.Count(err.Error(), "cannot export c"); n != 2 {
+		t.Errorf("got %v failures of c in %q, want 2", n, err)
+	}
+	if n := players[0].wins + players[0].draws + players[0].losses; n != 4 {
+		t.Errorf("a played %v episodes, want 4", n)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ANSI escape codes of the terminal UI
+const (
+	ansiClear   = "\x1b[H\x1b[2J"
+	ansiReverse = "\x1b[7m"    // the cursor
+	ansiLast    = "\x1b[1;33m" // the latest move, in bold yellow
+	ansiReset   = "\x1b[0m"
+	ansiHide    = "\x1b[?25l" // hide the terminal's own cursor
+	ansiShow    = "\x1b[?25h"
+)
+
+// terminal is the full-screen terminal UI of a human player: the board is drawn with ANSI
+// escape codes, and the move is chosen with the arrow keys read in raw mode. Raw mode is only
+// on while a human chooses a move, so the prompts work as usual in between. Each human has its
+// own, which shows the plan boards of the verbose opponent in its session.
+type terminal struct {
+	planTitle string // title of the plan board
+	plan      board  // latest plan board of a verbose agent in the episode; none if nil
+}
+
+// human players use the terminal UI rather than the line prompts; set by -tui before any session
+var terminalUI bool
+
+// the human quits the session from the terminal UI
+var errQuit = errors.New("the human quits the session")
+
+// planAgent is an agent that can show its plan boards in the terminal UI of its opponent
+type planAgent interface {
+	showPlans(t *terminal) // show the plan boards of the session in t; nowhere if nil
+}
+
+// hand the plan board of a verbose agent to the side panel of the terminal UI, if there's one
+func (t *terminal) showPlan(title string, plan board) {
+	if t != nil {
+		t.planTitle, t.plan = title, plan
+	}
+	return
+}
+
+// run stty on the terminal and return its output
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// let the human choose an empty location with the cursor, which starts where it was left;
+// errQuit is returned if the human quits
+func (t *terminal) chooseMove(env environment, symbol string, h *human) (location, error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return location{}, fmt.Errorf("not a terminal: %v", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return location{}, err
+	}
+	fmt.Print(ansiHide)
+	defer func() {
+		fmt.Print(ansiShow + ansiClear)
+		stty(saved)
+	}()
+
+	// the cursor starts where it was left, or at the first empty location
+	cursor := h.cursor
+	if cursor[0] >= env.spec.rows || cursor[1] >= env.spec.cols || env.board[cursor[0]][cursor[1]] != "" {
+		cursor = firstEmpty(env.board)
+	}
+	message := ""
+	key := make([]byte, 8)
+	for {
+		t.draw(env, symbol, h, cursor, message)
+		message = ""
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return location{}, err
+		}
+		switch k := string(key[:n]); k {
+		case "\x1b[A", "k", "w":
+			cursor[0] = (cursor[0] + env.spec.rows - 1) % env.spec.rows
+		case "\x1b[B", "j", "s":
+			cursor[0] = (cursor[0] + 1) % env.spec.rows
+		case "\x1b[D", "h", "a":
+			cursor[1] = (cursor[1] + env.spec.cols - 1) % env.spec.cols
+		case "\x1b[C", "l", "d":
+			cursor[1] = (cursor[1] + 1) % env.spec.cols
+		case "\r", "\n", " ":
+			if env.board[cursor[0]][cursor[1]] != "" {
+				message = fmt.Sprintf("row %v, column %v is taken", cursor[0], cursor[1])
+				continue
+			}
+			h.cursor = cursor
+			return cursor, nil
+		case "\x03", "q": // ctrl-c is a key in raw mode
+			return location{}, errQuit
+		}
+	}
+}
+
+// first empty location of the board, row by row
+func firstEmpty(b board) location {
+	for irow, row := range b {
+		for icol, element := range row {
+			if element == "" {
+				return location{irow, icol}
+			}
+		}
+	}
+	return location{}
+}
+
+// draw the board with the cursor and the latest move, the session's results and the plan
+// board of a verbose opponent beside it
+func (t *terminal) draw(env environment, symbol string, h *human, cursor location, message string) {
+	// board, with row and column numbers; every line is 6 characters per column plus 4 wide
+	width := 6*env.spec.cols + 4
+	header := "    "
+	for icol := 0; icol < env.spec.cols; icol++ {
+		header += fmt.Sprintf("  %-4v", icol)
+	}
+	border := "   " + strings.Repeat("-", 6*env.spec.cols+1)
+	left := []string{fmt.Sprintf("%-*v", width, header), border}
+	started := getEmpties(env.board) < env.spec.rows*env.spec.cols
+	for irow, row := range env.board {
+		line := fmt.Sprintf("%2v |", irow)
+		for icol, element := range row {
+			cell := strings.TrimSuffix(padSymbol(element), "|")
+			switch {
+			case location{irow, icol} == cursor:
+				cell = ansiReverse + cell + ansiReset
+			case started && location{irow, icol} == env.lastMove:
+				cell = ansiLast + cell + ansiReset
+			}
+			line += cell + "|"
+		}
+		left = append(left, line, border)
+	}
+
+	// plan board, level with the board
+	var right []string
+	if t.plan != nil {
+		right = append(right, t.planTitle)
+		for _, line := range strings.Split(strings.TrimRight(printBoard(&t.plan, false), "\n"), "\n") {
+			right = append(right, strings.TrimRight(line, " "))
+		}
+	}
+
+	lines := []string{
+		fmt.Sprintf("You play %v on a %v board - won %v, lost %v, drawn %v in this session", symbol, env.spec, h.wins, h.losses, h.draws),
+		"",
+	}
+	for i := 0; i < len(left) || i < len(right); i++ {
+		line := strings.Repeat(" ", width)
+		if i < len(left) {
+			line = left[i]
+		}
+		if i < len(right) {
+			line += "    " + right[i]
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "", "arrow keys or hjkl: move the cursor / enter or space: play / q: quit", message)
+	fmt.Print(ansiClear + strings.Join(lines, "\r\n"))
+	return
+}
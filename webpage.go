@@ -0,0 +1,136 @@
+package main
+
+// indexPage is the page served to play from a browser: it lists the opponents, starts a game
+// and polls its state, sending a move on each click on an empty location
+const indexPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GoTick</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.board { border-collapse: collapse; margin: 1em 0; }
+table.board td { width: 2.5em; height: 2.5em; border: 1px solid #444; text-align: center; font-size: 1.4em; }
+table.board td.empty.open { cursor: pointer; background: #eef; }
+table.board td.last { background: #fd8; }
+#status { font-weight: bold; }
+#error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>GoTick</h1>
+<form id="new">
+<label>Your name <input id="name" value="guest"></label>
+<label>Opponent <select id="opponent"></select></label>
+<label>Board <input id="rows" type="number" min="1" value="3" size="2"> x
+<input id="cols" type="number" min="1" value="3" size="2">, k
+<input id="k" type="number" min="1" value="3" size="2"></label>
+<label>First <select id="first">
+<option value="random">random</option><option value="user">you</option><option value="opponent">opponent</option>
+</select></label>
+<button type="submit">New game</button>
+</form>
+<p id="status"></p>
+<table class="board" id="board"></table>
+<p id="error"></p>
+<script>
+var game = null, timer = null;
+
+function $(id) { return document.getElementById(id); }
+
+function api(method, path, body) {
+  var init = { method: method };
+  if (body !== undefined) {
+    init.headers = { "Content-Type": "application/json" };
+    init.body = JSON.stringify(body);
+  }
+  return fetch(path, init).then(function (r) {
+    if (r.status === 204) { return null; }
+    return r.json().then(function (d) {
+      if (!r.ok) { throw new Error(d.error); }
+      return d;
+    });
+  });
+}
+
+function showError(e) { $("error").textContent = e ? e.message : ""; }
+
+function loadOpponents() {
+  api("GET", "/api/opponents").then(function (list) {
+    var sel = $("opponent");
+    sel.innerHTML = "";
+    list.forEach(function (o) {
+      var opt = document.createElement("option");
+      opt.value = o.name;
+      opt.textContent = o.name + " (" + o.kind + (o.busy ? ", busy" : "") + ")";
+      sel.appendChild(opt);
+    });
+  }).catch(showError);
+}
+
+function render(st) {
+  var table = $("board");
+  table.innerHTML = "";
+  var open = st.your_turn && !st.over;
+  st.board.forEach(function (row, i) {
+    var tr = table.insertRow();
+    row.forEach(function (cell, j) {
+      var td = tr.insertCell();
+      td.textContent = cell;
+      if (cell === "") {
+        td.className = "empty" + (open ? " open" : "");
+        if (open) { td.onclick = function () { move(i, j); }; }
+      }
+      if (st.last_move && st.last_move[0] === i && st.last_move[1] === j) { td.className += " last"; }
+    });
+  });
+  var status;
+  if (st.over) {
+    if (st.error) {
+      status = "Game failed - " + st.error;
+    } else if (st.abandoned) {
+      status = "Game abandoned";
+    } else {
+      status = st.winner === "" ? "Game over - draw" : (st.winner === st.symbol ? "Game over - you win" : "Game over - " + st.opponent + " wins");
+    }
+    clearInterval(timer);
+    loadOpponents();
+  } else if (st.symbol === "") {
+    status = "Starting...";
+  } else {
+    status = "You play " + st.symbol + " against " + st.opponent + " (" + st.k + " in a row) - " + (open ? "your turn" : st.opponent + " is thinking");
+  }
+  $("status").textContent = status;
+}
+
+function poll() {
+  api("GET", "/api/games/" + game).then(render).catch(showError);
+}
+
+function move(row, col) {
+  showError(null);
+  api("POST", "/api/games/" + game + "/moves", { row: row, col: col }).then(poll).catch(showError);
+}
+
+$("new").onsubmit = function (ev) {
+  ev.preventDefault();
+  showError(null);
+  var req = {
+    name: $("name").value,
+    opponent: $("opponent").value,
+    board: { rows: +$("rows").value, cols: +$("cols").value, k: +$("k").value },
+    first: $("first").value
+  };
+  api("POST", "/api/games", req).then(function (d) {
+    game = d.id;
+    clearInterval(timer);
+    timer = setInterval(poll, 500);
+    poll();
+  }).catch(showError);
+};
+
+loadOpponents();
+</script>
+</body>
+</html>
+`